@@ -1,17 +1,153 @@
 package main
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"io"
 	"os"
 )
 
-func command(w io.Writer, c conf) error {
+func command(w io.Writer, c conf) (err error) {
 	// You can't pass "--file" and "--execute" together
 	if c.templateFilePath != "" && c.stdinTemplateFile != "" {
 		return &conflictingArgsError{"file", "execute"}
 	}
 
-	tg := &tgen{Strict: c.strictMode}
+	// Directory mode can't be combined with a single template
+	if c.inputDir != "" {
+		if c.templateFilePath != "" {
+			return &conflictingArgsError{"input-dir", "file"}
+		}
+
+		if c.stdinTemplateFile != "" {
+			return &conflictingArgsError{"input-dir", "execute"}
+		}
+
+		if c.outputDir == "" {
+			return &missingArgError{"input-dir", "output-dir"}
+		}
+	}
+
+	if c.outputFile != "" && c.inputDir != "" {
+		return &conflictingArgsError{"output", "input-dir"}
+	}
+
+	if c.splitOutput != "" {
+		if c.outputFile != "" {
+			return &conflictingArgsError{"split-output", "output"}
+		}
+
+		if c.inputDir != "" {
+			return &conflictingArgsError{"split-output", "input-dir"}
+		}
+	}
+
+	if c.outputDir != "" && c.inputDir == "" {
+		return &missingArgError{"output-dir", "input-dir"}
+	}
+
+	tg, err := loadInputs(c)
+	if err != nil {
+		return err
+	}
+
+	// Store the values generated by "persistent" even when rendering fails,
+	// since some outputs may have been written with them already
+	if state := tg.state; state != nil {
+		defer func() {
+			err = errors.Join(err, state.save())
+		}()
+	}
+
+	// Render every file in the input directory
+	if c.inputDir != "" {
+		return tg.renderDirectory(c.inputDir, c.outputDir)
+	}
+
+	// Apply the template's front matter, if any
+	tg, target, err := tg.withFrontMatter()
+	if err != nil {
+		return err
+	}
+
+	if target.skip {
+		return nil
+	}
+
+	// Write each YAML document to its own file
+	if c.splitOutput != "" {
+		var buf bytes.Buffer
+		if err := tg.render(&buf); err != nil {
+			return err
+		}
+
+		return tg.splitDocuments(buf.Bytes(), c.splitOutput, c.splitName)
+	}
+
+	// An explicit --output takes precedence over the front matter
+	outputFile := c.outputFile
+	if outputFile == "" {
+		outputFile = target.output
+	}
+
+	// Render into a buffer first so the output file is only replaced
+	// when rendering succeeds
+	if outputFile != "" {
+		var buf bytes.Buffer
+		if err := tg.render(&buf); err != nil {
+			return err
+		}
+
+		return writeFileIfChanged(outputFile, buf.Bytes(), target.mode)
+	}
+
+	// Render code
+	return tg.render(w)
+}
+
+// loadInputs creates a tgen instance with the template, delimiters,
+// environment and values from the given configuration loaded
+func loadInputs(c conf) (*tgen, error) {
+	tg := &tgen{Strict: c.strictMode, collectErrors: c.collectErrors, onFileRead: c.onFileRead}
+
+	if c.collectErrors && !c.strictMode {
+		return nil, &missingArgError{"collect-errors", "strict"}
+	}
+
+	// Isolate rendering from the host, which has to happen before loading
+	// environment files so they can't reference the OS environment
+	if c.hermetic {
+		h, err := c.hermeticConfig()
+		if err != nil {
+			return nil, err
+		}
+
+		tg.hermetic = h
+	} else if c.clock != "" {
+		return nil, &missingArgError{"clock", "hermetic"}
+	}
+
+	tg.seed = c.seed
+
+	policy, err := c.policyConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	tg.policy = policy
+
+	if tg.allowRead, err = c.allowReadRoots(); err != nil {
+		return nil, err
+	}
+
+	if c.stateFile != "" {
+		if tg.state, err = loadState(c.stateFile); err != nil {
+			return nil, err
+		}
+
+		tg.job = c.job
+	}
 
 	// Read template from "-x" or "--execute" flag
 	if c.stdinTemplateFile != "" {
@@ -29,45 +165,61 @@ func command(w io.Writer, c conf) error {
 		}
 
 		if err != nil {
-			return err
+			return nil, err
+		}
+	}
+
+	// Load helper templates
+	for _, include := range c.includes {
+		if err := tg.loadIncludes(include); err != nil {
+			return nil, err
 		}
 	}
 
 	// Set delimiters
 	if c.customDelimiters != "" {
 		if err := tg.setDelimiters(c.customDelimiters); err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	// Load environment variable file
-	if c.environmentFile != "" {
-		if err := tg.loadEnvValues(c.environmentFile); err != nil {
-			return err
+	// Load environment variable files, each one layered on top of the
+	// previous
+	switch c.envPrecedence {
+	case "", envPrecedenceOS:
+	case envPrecedenceFiles:
+		tg.envFilesFirst = true
+	default:
+		return nil, fmt.Errorf("unknown environment precedence %q: valid options are %q or %q", c.envPrecedence, envPrecedenceOS, envPrecedenceFiles)
+	}
+
+	tg.envCaseSensitive = c.envCaseSensitive
+	for _, envFile := range c.environmentFiles {
+		if err := tg.loadEnvValues(envFile); err != nil {
+			return nil, err
 		}
 	}
 
-	// Load yaml values file
-	if c.valuesFile != "" {
-		if err := tg.loadYAMLValues(c.valuesFile); err != nil {
-			return err
+	// Load yaml values files, each one merged on top of the previous
+	for _, valuesFile := range c.allValuesFiles() {
+		if err := tg.loadValues(valuesFile, c.valuesFormat); err != nil {
+			return nil, err
 		}
 	}
 
-	// Parse and merge set values
-	if len(c.setValues) > 0 {
-		if err := tg.mergeSetValues(c.setValues); err != nil {
-			return err
+	// Parse and merge the values of every set flag, in command line order
+	if len(c.setFlags) > 0 {
+		if err := tg.mergeSetFlags(c.setFlags); err != nil {
+			return nil, err
 		}
 	}
 
-	// Parse and merge set-string values
-	if len(c.setStringValues) > 0 {
-		if err := tg.mergeSetStringValues(c.setStringValues); err != nil {
-			return err
+	// Validate the final merged values against the schema, if any
+	if schemapath := c.schemaPath(); schemapath != "" {
+		if err := tg.validateValues(schemapath); err != nil {
+			return nil, err
 		}
 	}
 
-	// Render code
-	return tg.render(w)
+	return tg, nil
 }
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// renderDirectory walks inputDir and renders every template file found into
+// the same relative path under outputDir, using the values, environment and
+// delimiters already loaded into t. Files that aren't templates are copied
+// verbatim. File modes are kept for both rendered and copied files. Helper
+// libraries named "_*.tpl" are parsed alongside every template instead of
+// being rendered.
+func (t *tgen) renderDirectory(inputDir, outputDir string) error {
+	info, err := os.Stat(inputDir)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("input directory %q is not a directory", inputDir)
+	}
+
+	// If the output directory lives inside the input directory, we need to
+	// skip it while walking, otherwise we would render our own output.
+	absOutput, err := filepath.Abs(outputDir)
+	if err != nil {
+		return err
+	}
+
+	// Helper libraries named "_*.tpl" are available to every template
+	// in the directory, and are not rendered themselves
+	tg := *t
+	tg.helpers = slices.Clone(t.helpers)
+
+	err = filepath.WalkDir(inputDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if abs, err := filepath.Abs(path); err == nil && abs == absOutput {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if !isHelperFile(path) {
+			return nil
+		}
+
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		tg.addHelper(path, string(contents))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	written := make(map[string]string)
+	return filepath.WalkDir(inputDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if abs, err := filepath.Abs(path); err == nil && abs == absOutput {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if isHelperFile(path) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(inputDir, path)
+		if err != nil {
+			return err
+		}
+
+		// Front matter can point several templates to the same output,
+		// which is checked before anything is written to it
+		claim := func(dst string) error {
+			if previous, found := written[dst]; found {
+				return fmt.Errorf("output path %q was already written by %q", dst, previous)
+			}
+
+			written[dst] = path
+			return nil
+		}
+
+		if err := tg.renderDirectoryFile(path, rel, outputDir, claim); err != nil {
+			return &renderFileError{path: path, original: err}
+		}
+
+		return nil
+	})
+}
+
+// renderDirectoryFile renders or copies a single file from the input directory
+// into its destination under outputDir, creating any parent directories
+// needed. Once the destination is known, and before rendering, it's passed
+// to claim, which can stop the file from being written by returning an
+// error. Files skipped by their front matter aren't claimed.
+func (t *tgen) renderDirectoryFile(src, rel, outputDir string, claim func(dst string) error) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	// Symbolic links to directories are not followed
+	if info.IsDir() {
+		return nil
+	}
+
+	contents, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	output := contents
+	mode := info.Mode().Perm()
+	dst := filepath.Join(outputDir, rel)
+
+	if !isBinary(contents) {
+		tg := *t
+		tg.setTemplate(src, string(contents))
+
+		rendered, target, err := tg.withFrontMatter()
+		if err != nil {
+			return err
+		}
+
+		if target.skip {
+			return nil
+		}
+
+		if target.output != "" {
+			if !filepath.IsLocal(target.output) {
+				return fmt.Errorf("front matter output path %q must be relative and within the output directory", target.output)
+			}
+
+			dst = filepath.Join(outputDir, target.output)
+		}
+
+		if target.mode != 0 {
+			mode = target.mode
+		}
+
+		if err := claim(dst); err != nil {
+			return err
+		}
+
+		// Files with front matter are always rendered, since their
+		// delimiters might have been overridden
+		if rendered.isTemplate([]byte(rendered.templateFileContent)) {
+			var buf bytes.Buffer
+			if err := rendered.render(&buf); err != nil {
+				return err
+			}
+
+			output = buf.Bytes()
+		}
+	} else if err := claim(dst); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+
+	return writeFileIfChanged(dst, output, mode)
+}
+
+// isBinary reports whether contents look like binary data rather than text
+func isBinary(contents []byte) bool {
+	return bytes.IndexByte(contents, 0) >= 0 || !utf8.Valid(contents)
+}
+
+// isTemplate reports whether the given contents should be rendered as a
+// template. Binary files, or files that never use the opening delimiter,
+// are considered plain files and copied as-is.
+func (t *tgen) isTemplate(contents []byte) bool {
+	if isBinary(contents) {
+		return false
+	}
+
+	delim := t.preDelimiter
+	if delim == "" {
+		delim = "{{"
+	}
+
+	return strings.Contains(string(contents), delim)
+}
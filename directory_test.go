@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, path, content string, mode os.FileMode) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte(content), mode); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	if err := os.Chmod(path, mode); err != nil {
+		t.Fatalf("Failed to set file mode: %v", err)
+	}
+}
+
+func TestRenderDirectory(t *testing.T) {
+	input := t.TempDir()
+	output := filepath.Join(t.TempDir(), "out")
+
+	writeTestFile(t, filepath.Join(input, "hello.txt"), "Hello, {{ .name }}!", 0o644)
+	writeTestFile(t, filepath.Join(input, "nested", "deep", "run.sh"), "#!/bin/sh\necho {{ .name }}\n", 0o755)
+	writeTestFile(t, filepath.Join(input, "plain.txt"), "no templating here", 0o600)
+	writeTestFile(t, filepath.Join(input, "binary.bin"), "{{\x00\xff", 0o644)
+
+	tg := &tgen{yamlValues: map[string]any{"name": "World"}}
+	if err := tg.renderDirectory(input, output); err != nil {
+		t.Fatalf("renderDirectory() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+		mode os.FileMode
+	}{
+		{path: "hello.txt", want: "Hello, World!", mode: 0o644},
+		{path: "nested/deep/run.sh", want: "#!/bin/sh\necho World\n", mode: 0o755},
+		{path: "plain.txt", want: "no templating here", mode: 0o600},
+		{path: "binary.bin", want: "{{\x00\xff", mode: 0o644},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			full := filepath.Join(output, filepath.FromSlash(tt.path))
+
+			got, err := os.ReadFile(full)
+			if err != nil {
+				t.Fatalf("unable to read rendered file: %v", err)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("rendered content = %q, want %q", got, tt.want)
+			}
+
+			info, err := os.Stat(full)
+			if err != nil {
+				t.Fatalf("unable to stat rendered file: %v", err)
+			}
+
+			if info.Mode().Perm() != tt.mode {
+				t.Errorf("rendered mode = %v, want %v", info.Mode().Perm(), tt.mode)
+			}
+		})
+	}
+}
+
+func TestRenderDirectoryOutputInsideInput(t *testing.T) {
+	input := t.TempDir()
+	output := filepath.Join(input, "out")
+
+	writeTestFile(t, filepath.Join(input, "a.txt"), "{{ .name }}", 0o644)
+
+	tg := &tgen{yamlValues: map[string]any{"name": "first"}}
+	if err := tg.renderDirectory(input, output); err != nil {
+		t.Fatalf("renderDirectory() unexpected error: %v", err)
+	}
+
+	// A second run must not pick up the files rendered by the first one
+	tg.yamlValues = map[string]any{"name": "second"}
+	if err := tg.renderDirectory(input, output); err != nil {
+		t.Fatalf("renderDirectory() unexpected error: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(output, "out")); !os.IsNotExist(err) {
+		t.Errorf("output directory was rendered into itself")
+	}
+}
+
+func TestRenderDirectoryErrorNamesFile(t *testing.T) {
+	input := t.TempDir()
+	broken := filepath.Join(input, "sub", "broken.txt")
+
+	writeTestFile(t, filepath.Join(input, "ok.txt"), "{{ .name }}", 0o644)
+	writeTestFile(t, broken, "{{ .name ", 0o644)
+
+	tg := &tgen{}
+	err := tg.renderDirectory(input, t.TempDir())
+	if err == nil {
+		t.Fatal("renderDirectory() expected error but got none")
+	}
+
+	var fileErr *renderFileError
+	if !errors.As(err, &fileErr) {
+		t.Fatalf("renderDirectory() error = %T, want *renderFileError", err)
+	}
+
+	if fileErr.path != broken {
+		t.Errorf("renderDirectory() error path = %q, want %q", fileErr.path, broken)
+	}
+}
+
+func TestCommandDirectoryFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		conf conf
+	}{
+		{name: "input-dir with file", conf: conf{inputDir: "a", outputDir: "b", templateFilePath: "c"}},
+		{name: "input-dir with execute", conf: conf{inputDir: "a", outputDir: "b", stdinTemplateFile: "c"}},
+		{name: "input-dir without output-dir", conf: conf{inputDir: "a"}},
+		{name: "output-dir without input-dir", conf: conf{outputDir: "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := command(nil, tt.conf); err == nil {
+				t.Errorf("command() expected error but got none")
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"github.com/patrickdappollonio/tgen/render"
+)
+
+// Formats for --error-format
+const (
+	errorFormatText = "text"
+	errorFormatJSON = "json"
+)
+
+// templateErrors returns err as a list of template errors, with the errors
+// that aren't about a template classified as "io" or "other"
+func templateErrors(err error) []*render.TemplateError {
+	var report *render.StrictReportError
+	if errors.As(err, &report) {
+		errs := append([]*render.TemplateError{}, report.Problems...)
+		if report.Stopped != nil {
+			errs = append(errs, templateErrors(report.Stopped)...)
+		}
+
+		return errs
+	}
+
+	var terr *render.TemplateError
+	if errors.As(err, &terr) {
+		return []*render.TemplateError{terr}
+	}
+
+	kind := render.ErrorKindOther
+	if pathErr := (*fs.PathError)(nil); errors.As(err, &pathErr) {
+		kind = render.ErrorKindIO
+	}
+
+	return []*render.TemplateError{{Kind: kind, Message: err.Error()}}
+}
+
+// writeError writes err to w in the given format
+func writeError(w io.Writer, err error, format string) {
+	if format != errorFormatJSON {
+		fmt.Fprintf(w, "Error: %s\n", err)
+		return
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(templateErrors(err))
+}
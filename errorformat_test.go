@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/patrickdappollonio/tgen/render"
+	"github.com/spf13/cobra"
+)
+
+func TestWriteErrorJSON(t *testing.T) {
+	configs, _ := parseRenderFlags(t, "-s", "--collect-errors", "-x", "{{ .a }}\n{{ .b }}")
+	err := command(&bytes.Buffer{}, *configs)
+
+	var buf bytes.Buffer
+	writeError(&buf, err, errorFormatJSON)
+
+	var got []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("writeError() wrote invalid JSON %q: %s", buf.String(), err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("writeError() wrote %d errors, want 2: %s", len(got), buf.String())
+	}
+
+	for i, e := range got {
+		if e["kind"] != string(render.ErrorKindMissingKey) || e["template"] != "/dev/stdin" || e["line"] != float64(i+1) || e["column"] != float64(4) {
+			t.Errorf("writeError() error %d = %v", i, e)
+		}
+	}
+
+	buf.Reset()
+	writeError(&buf, errors.New("some flag is wrong"), errorFormatJSON)
+
+	expected := `[{"kind":"other","line":0,"column":0,"message":"some flag is wrong"}]`
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, buf.Bytes()); err != nil || compact.String() != expected {
+		t.Errorf("writeError() = %s, want %s", buf.String(), expected)
+	}
+
+	buf.Reset()
+	writeError(&buf, errors.New("some flag is wrong"), errorFormatText)
+	if buf.String() != "Error: some flag is wrong\n" {
+		t.Errorf("writeError() = %q, want the text format", buf.String())
+	}
+}
+
+func TestErrorFormatFlag(t *testing.T) {
+	configs, _ := parseRenderFlags(t, "--error-format", "xml", "-x", "hello")
+
+	err := renderCommand(&cobra.Command{}, *configs)
+	if err == nil || !strings.Contains(err.Error(), `unknown error format "xml"`) {
+		t.Errorf("renderCommand() error = %v, want an unknown error format error", err)
+	}
+}
+
+func TestCollectErrorsReport(t *testing.T) {
+	configs, _ := parseRenderFlags(t, "-s", "--collect-errors", "-x", "{{ .a }}\n{{ .b }}")
+
+	err := command(&bytes.Buffer{}, *configs)
+	if err == nil {
+		t.Fatal("command() expected an error")
+	}
+
+	expected := "strict mode on: found 2 problem(s) while rendering:\n" +
+		"  - /dev/stdin:1:4: missing value: .a\n" +
+		"  - /dev/stdin:2:4: missing value: .b"
+
+	if err.Error() != expected {
+		t.Errorf("command() error = %q, want %q", err.Error(), expected)
+	}
+
+	configs, _ = parseRenderFlags(t, "--collect-errors", "-x", "{{ .a }}")
+
+	var missing *missingArgError
+	if err := command(&bytes.Buffer{}, *configs); !errors.As(err, &missing) {
+		t.Errorf("command() error = %v, want a missing --strict error", err)
+	}
+}
@@ -1,32 +1,59 @@
 package main
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"strings"
 
-type templateFuncError struct {
-	line     string
-	original error
+	"github.com/patrickdappollonio/tgen/internal/jsonschema"
+)
+
+type conflictingArgsError struct{ F1, F2 string }
+
+func (e *conflictingArgsError) Error() string {
+	return fmt.Sprintf("defined both --%s and --%s, only one must be used", e.F1, e.F2)
 }
 
-func (t templateFuncError) Error() string {
-	if t.line == "" {
-		return t.original.Error()
-	}
+type renderFileError struct {
+	path     string
+	original error
+}
 
-	return fmt.Sprintf("evaluating %s: %s", t.line, t.original)
+func (e *renderFileError) Error() string {
+	return fmt.Sprintf("unable to render file %q: %s", e.path, e.original)
 }
 
-func (t templateFuncError) Unwrap() error {
-	return t.original
+func (e *renderFileError) Unwrap() error {
+	return e.original
 }
 
-type missingKeyErr struct{ name string }
+type missingArgError struct{ F1, F2 string }
 
-func (e *missingKeyErr) Error() string {
-	return "strict mode on: missing value in values file: " + e.name
+func (e *missingArgError) Error() string {
+	return fmt.Sprintf("--%s requires --%s to be set", e.F1, e.F2)
 }
 
-type conflictingArgsError struct{ F1, F2 string }
+type schemaError struct {
+	path     string
+	original error
+}
 
-func (e *conflictingArgsError) Error() string {
-	return fmt.Sprintf("defined both --%s and --%s, only one must be used", e.F1, e.F2)
+func (e *schemaError) Error() string {
+	var verr *jsonschema.ValidationError
+	if !errors.As(e.original, &verr) {
+		return fmt.Sprintf("unable to use schema file %q: %s", e.path, e.original)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "values don't match schema file %q:", e.path)
+	for _, v := range verr.Violations {
+		sb.WriteString("\n  - ")
+		sb.WriteString(v.String())
+	}
+
+	return sb.String()
+}
+
+func (e *schemaError) Unwrap() error {
+	return e.original
 }
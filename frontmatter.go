@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// frontMatterSeparator opens and closes a front matter block
+const frontMatterSeparator = "---"
+
+// frontMatter is the metadata a template can declare in a leading YAML block
+// delimited by "---" lines
+type frontMatter struct {
+	// Output is the path where the rendered template is written. It's
+	// rendered as a template itself.
+	Output string `yaml:"output"`
+
+	// Values are merged on top of the loaded values for this template only
+	Values map[string]any `yaml:"values"`
+
+	// Skip is either a boolean or a template that renders to one. When
+	// true, the template produces no output.
+	Skip any `yaml:"skip"`
+
+	// Mode is the octal file mode of the output file, like "0755"
+	Mode any `yaml:"mode"`
+
+	// Delimiter overrides the template delimiters for this template
+	Delimiter string `yaml:"delimiter"`
+}
+
+// renderTarget describes where and whether a template should be written,
+// as declared by its front matter
+type renderTarget struct {
+	skip   bool
+	output string
+	mode   fs.FileMode
+}
+
+// splitFrontMatter finds a front matter block at the beginning of content.
+// It returns the parsed front matter, the number of lines it spans, and the
+// content that follows it. A leading YAML block is only considered front
+// matter when every key in it is a front matter key, so templates that start
+// with a YAML document separator, like Kubernetes manifests, are left as-is.
+func splitFrontMatter(content string) (*frontMatter, int, string, error) {
+	first, rest, found := strings.Cut(content, "\n")
+	if !found || strings.TrimRight(first, " \t\r") != frontMatterSeparator {
+		return nil, 0, content, nil
+	}
+
+	var block strings.Builder
+	lines := 1
+	for {
+		line, remaining, found := strings.Cut(rest, "\n")
+		lines++
+
+		if strings.TrimRight(line, " \t\r") == frontMatterSeparator {
+			rest = remaining
+			break
+		}
+
+		if !found {
+			// No closing separator, so this isn't front matter
+			return nil, 0, content, nil
+		}
+
+		block.WriteString(line)
+		block.WriteString("\n")
+		rest = remaining
+	}
+
+	var keys map[string]any
+	if err := yaml.Unmarshal([]byte(block.String()), &keys); err != nil || len(keys) == 0 {
+		return nil, 0, content, nil
+	}
+
+	for k := range keys {
+		switch k {
+		case "output", "values", "skip", "mode", "delimiter":
+		default:
+			return nil, 0, content, nil
+		}
+	}
+
+	var fm frontMatter
+	dec := yaml.NewDecoder(strings.NewReader(block.String()))
+	dec.KnownFields(true)
+	if err := dec.Decode(&fm); err != nil {
+		return nil, 0, content, fmt.Errorf("unable to parse front matter: %w", err)
+	}
+
+	return &fm, lines, rest, nil
+}
+
+// withFrontMatter processes the front matter of the loaded template, if any.
+// It returns a copy of t with the front matter removed from the template and
+// its values and delimiters applied, along with the declared render target.
+// The front matter is replaced by a template comment spanning the same
+// number of lines, so line numbers in errors still match the original file.
+func (t *tgen) withFrontMatter() (*tgen, renderTarget, error) {
+	var target renderTarget
+
+	fm, lines, body, err := splitFrontMatter(t.templateFileContent)
+	if err != nil {
+		return nil, target, fmt.Errorf("template file %q: %w", t.templateFileName, err)
+	}
+
+	if fm == nil {
+		return t, target, nil
+	}
+
+	tg := *t
+
+	if fm.Delimiter != "" {
+		if err := tg.setDelimiters(fm.Delimiter); err != nil {
+			return nil, target, fmt.Errorf("template file %q: front matter: %w", t.templateFileName, err)
+		}
+	}
+
+	if len(fm.Values) > 0 {
+		tg.mergeValues(fm.Values)
+	}
+
+	left, right := tg.preDelimiter, tg.postDelimiter
+	if left == "" || right == "" {
+		left, right = "{{", "}}"
+	}
+
+	tg.templateFileContent = left + "/*" + strings.Repeat("\n", lines) + "*/" + right + body
+
+	if target.skip, err = tg.evalSkip(fm.Skip); err != nil {
+		return nil, target, err
+	}
+
+	if fm.Output != "" {
+		if target.output, err = tg.renderExpression("output", fm.Output); err != nil {
+			return nil, target, err
+		}
+
+		target.output = strings.TrimSpace(target.output)
+	}
+
+	if target.mode, err = parseFileMode(fm.Mode); err != nil {
+		return nil, target, fmt.Errorf("template file %q: front matter: %w", t.templateFileName, err)
+	}
+
+	return &tg, target, nil
+}
+
+// evalSkip evaluates the "skip" front matter field
+func (t *tgen) evalSkip(skip any) (bool, error) {
+	switch v := skip.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return v, nil
+	case string:
+		out, err := t.renderExpression("skip", v)
+		if err != nil {
+			return false, err
+		}
+
+		out = strings.TrimSpace(out)
+		if out == "" {
+			return false, nil
+		}
+
+		b, err := strconv.ParseBool(out)
+		if err != nil {
+			return false, fmt.Errorf("template file %q: front matter: skip must render to a boolean, got %q", t.templateFileName, out)
+		}
+
+		return b, nil
+	default:
+		return false, fmt.Errorf("template file %q: front matter: skip must be a boolean or a template, got %T", t.templateFileName, skip)
+	}
+}
+
+// renderExpression renders a front matter field as a template, with the same
+// values, environment and delimiters as the template itself
+func (t *tgen) renderExpression(field, expr string) (string, error) {
+	tg := t.helper(t.templateFileName+":"+field, expr)
+
+	var buf bytes.Buffer
+	if err := tg.render(&buf); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// parseFileMode parses an octal file mode given either as a string, like
+// "0755", or as a number, which YAML decodes from unquoted octal literals
+func parseFileMode(mode any) (fs.FileMode, error) {
+	switch v := mode.(type) {
+	case nil:
+		return 0, nil
+	case int:
+		if v <= 0 || v > 0o777 {
+			return 0, fmt.Errorf("invalid file mode %o", v)
+		}
+
+		return fs.FileMode(v), nil
+	case string:
+		n, err := strconv.ParseUint(strings.TrimPrefix(v, "0o"), 8, 32)
+		if err != nil || n == 0 || n > 0o777 {
+			return 0, fmt.Errorf("invalid file mode %q", v)
+		}
+
+		return fs.FileMode(n), nil
+	default:
+		return 0, fmt.Errorf("invalid file mode %v", mode)
+	}
+}
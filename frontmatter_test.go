@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSplitFrontMatter(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		found   bool
+		lines   int
+		body    string
+		wantErr bool
+	}{
+		{
+			name:    "no front matter",
+			content: "Hello {{ .name }}",
+			body:    "Hello {{ .name }}",
+		},
+		{
+			name:    "front matter",
+			content: "---\noutput: out.txt\nmode: \"0600\"\n---\nHello",
+			found:   true,
+			lines:   4,
+			body:    "Hello",
+		},
+		{
+			name:    "kubernetes document separator",
+			content: "---\napiVersion: v1\nkind: ConfigMap\n---\nkind: Secret\n",
+			body:    "---\napiVersion: v1\nkind: ConfigMap\n---\nkind: Secret\n",
+		},
+		{
+			name:    "unclosed block",
+			content: "---\noutput: out.txt\n",
+			body:    "---\noutput: out.txt\n",
+		},
+		{
+			name:    "invalid field type",
+			content: "---\nvalues: [1, 2]\n---\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fm, lines, body, err := splitFrontMatter(tt.content)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("splitFrontMatter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if (fm != nil) != tt.found {
+				t.Errorf("splitFrontMatter() found = %v, want %v", fm != nil, tt.found)
+			}
+
+			if lines != tt.lines {
+				t.Errorf("splitFrontMatter() lines = %d, want %d", lines, tt.lines)
+			}
+
+			if body != tt.body {
+				t.Errorf("splitFrontMatter() body = %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
+
+func TestFrontMatterValuesAndDelimiter(t *testing.T) {
+	tg := &tgen{yamlValues: map[string]any{"name": "World", "greeting": "Hello"}}
+	tg.setTemplate("test", "---\ndelimiter: \"[[]]\"\nvalues:\n  name: Front Matter\n---\n[[ .greeting ]], [[ .name ]]! {{ not-a-template }}")
+
+	tg, target, err := tg.withFrontMatter()
+	if err != nil {
+		t.Fatalf("withFrontMatter() unexpected error: %v", err)
+	}
+
+	if target.skip || target.output != "" || target.mode != 0 {
+		t.Errorf("withFrontMatter() target = %+v, want empty", target)
+	}
+
+	var buf bytes.Buffer
+	if err := tg.render(&buf); err != nil {
+		t.Fatalf("render() unexpected error: %v", err)
+	}
+
+	if expected := "Hello, Front Matter! {{ not-a-template }}"; buf.String() != expected {
+		t.Errorf("render() = %q, want %q", buf.String(), expected)
+	}
+}
+
+func TestFrontMatterPreservesLineNumbers(t *testing.T) {
+	tg := &tgen{}
+	tg.setTemplate("test", "---\nskip: false\n---\nfirst line\n{{ if }}")
+
+	tg, _, err := tg.withFrontMatter()
+	if err != nil {
+		t.Fatalf("withFrontMatter() unexpected error: %v", err)
+	}
+
+	err = tg.render(&bytes.Buffer{})
+	if err == nil {
+		t.Fatal("render() expected error but got none")
+	}
+
+	if !strings.Contains(err.Error(), "test:5:") {
+		t.Errorf("render() error = %q, want it to point at line 5", err.Error())
+	}
+}
+
+func TestRenderDirectoryFrontMatter(t *testing.T) {
+	input := t.TempDir()
+	output := filepath.Join(t.TempDir(), "out")
+
+	writeTestFile(t, filepath.Join(input, "service.tpl"), "---\noutput: \"{{ .name }}/service.yaml\"\nmode: \"0600\"\n---\nname: {{ .name }}\n", 0o644)
+	writeTestFile(t, filepath.Join(input, "disabled.txt"), "---\nskip: \"{{ not .enabled }}\"\n---\nnever rendered\n", 0o644)
+	writeTestFile(t, filepath.Join(input, "run.sh"), "---\nmode: 0755\ndelimiter: \"<<>>\"\n---\necho <<.name>>\n", 0o644)
+
+	tg := &tgen{yamlValues: map[string]any{"name": "api", "enabled": false}}
+	if err := tg.renderDirectory(input, output); err != nil {
+		t.Fatalf("renderDirectory() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+		mode os.FileMode
+	}{
+		{path: "api/service.yaml", want: "name: api\n", mode: 0o600},
+		{path: "run.sh", want: "echo api\n", mode: 0o755},
+	}
+
+	for _, tt := range tests {
+		full := filepath.Join(output, filepath.FromSlash(tt.path))
+
+		got, err := os.ReadFile(full)
+		if err != nil {
+			t.Fatalf("unable to read rendered file: %v", err)
+		}
+
+		if string(got) != tt.want {
+			t.Errorf("%s: rendered content = %q, want %q", tt.path, got, tt.want)
+		}
+
+		info, err := os.Stat(full)
+		if err != nil {
+			t.Fatalf("unable to stat rendered file: %v", err)
+		}
+
+		if info.Mode().Perm() != tt.mode {
+			t.Errorf("%s: rendered mode = %v, want %v", tt.path, info.Mode().Perm(), tt.mode)
+		}
+	}
+
+	for _, name := range []string{"service.tpl", "disabled.txt"} {
+		if _, err := os.Stat(filepath.Join(output, name)); !os.IsNotExist(err) {
+			t.Errorf("expected %s not to be written, got error %v", name, err)
+		}
+	}
+}
+
+func TestRenderDirectoryFrontMatterOutputErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+
+		// outputs are the files expected in the output directory anyway
+		outputs map[string]string
+	}{
+		{
+			name:  "escaping output path",
+			files: map[string]string{"a.txt": "---\noutput: ../escape.txt\n---\n"},
+			want:  "must be relative and within the output directory",
+		},
+		{
+			name: "colliding output paths",
+			files: map[string]string{
+				"a.txt": "---\noutput: same.txt\n---\nfirst",
+				"b.txt": "---\noutput: same.txt\n---\nsecond",
+			},
+			want:    "was already written by",
+			outputs: map[string]string{"same.txt": "first"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := t.TempDir()
+			for name, content := range tt.files {
+				writeTestFile(t, filepath.Join(input, name), content, 0o644)
+			}
+
+			output := filepath.Join(t.TempDir(), "out")
+
+			tg := &tgen{}
+			err := tg.renderDirectory(input, output)
+			if err == nil {
+				t.Fatal("renderDirectory() expected error but got none")
+			}
+
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("renderDirectory() error = %q, want it to contain %q", err.Error(), tt.want)
+			}
+
+			for name, expected := range tt.outputs {
+				got, err := os.ReadFile(filepath.Join(output, name))
+				if err != nil {
+					t.Fatalf("unable to read output %s: %v", name, err)
+				}
+
+				if string(got) != expected {
+					t.Errorf("output %s = %q, want %q", name, got, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestCommandFrontMatterOutput(t *testing.T) {
+	dir := t.TempDir()
+	tpl := filepath.Join(dir, "template.txt")
+	target := filepath.Join(dir, "rendered.txt")
+
+	writeTestFile(t, tpl, "---\noutput: "+target+"\n---\nHello", 0o644)
+
+	var stdout bytes.Buffer
+	if err := command(&stdout, conf{templateFilePath: tpl}); err != nil {
+		t.Fatalf("command() unexpected error: %v", err)
+	}
+
+	if stdout.Len() != 0 {
+		t.Errorf("command() wrote %q to stdout, want nothing", stdout.String())
+	}
+
+	got, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatalf("unable to read rendered file: %v", err)
+	}
+
+	if string(got) != "Hello" {
+		t.Errorf("rendered content = %q, want %q", got, "Hello")
+	}
+}
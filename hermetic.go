@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/patrickdappollonio/tgen/tfuncs"
+)
+
+// hermeticConfig returns the hermetic settings for the configuration. The
+// file functions can read from the directory of the template, or the
+// working directory for inline and stdin templates, the input directory,
+// every include and every --allow-read directory.
+func (c conf) hermeticConfig() (*tfuncs.Hermetic, error) {
+	now, err := parseClock(c.clock)
+	if err != nil {
+		return nil, err
+	}
+
+	var roots []string
+	switch c.templateFilePath {
+	case "":
+		if c.inputDir == "" {
+			wd, err := os.Getwd()
+			if err != nil {
+				return nil, err
+			}
+
+			roots = append(roots, wd)
+		}
+	case "-":
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+
+		roots = append(roots, wd)
+	default:
+		roots = append(roots, filepath.Dir(c.templateFilePath))
+	}
+
+	if c.inputDir != "" {
+		roots = append(roots, c.inputDir)
+	}
+
+	for _, include := range c.includes {
+		if info, err := os.Stat(include); err == nil && !info.IsDir() {
+			include = filepath.Dir(include)
+		}
+
+		roots = append(roots, include)
+	}
+
+	roots = append(roots, c.allowRead...)
+
+	var seed int64
+	if c.seed != nil {
+		seed = *c.seed
+	}
+
+	return &tfuncs.Hermetic{Roots: roots, Seed: seed, Now: now}, nil
+}
+
+// parseClock parses the time given to --clock, either in RFC 3339 format or
+// as seconds since the Unix epoch. It defaults to the Unix epoch.
+func parseClock(s string) (time.Time, error) {
+	if s == "" {
+		return time.Unix(0, 0).UTC(), nil
+	}
+
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(secs, 0).UTC(), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --clock value %q: must be an RFC 3339 time, like \"2006-01-02T15:04:05Z\", or seconds since the Unix epoch", s)
+	}
+
+	return t, nil
+}
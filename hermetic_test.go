@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHermeticCommand(t *testing.T) {
+	t.Setenv("TGEN_TEST_HOST", "from-os")
+
+	dir := t.TempDir()
+	env := filepath.Join(dir, "build.env")
+	tpl := filepath.Join(dir, "templates", "build.tpl")
+	writeTestFile(t, env, "NAME=app\nHOST=${TGEN_TEST_HOST:-none}\n", 0o644)
+	writeTestFile(t, filepath.Join(dir, "templates", "data.txt"), "data", 0o644)
+	writeTestFile(t, filepath.Join(dir, "secret.txt"), "secret", 0o644)
+	writeTestFile(t, tpl, `{{ env "NAME" }} {{ env "HOST" }} {{ env "TGEN_TEST_HOST" }} {{ readfile .data }} {{ uuidv4 }} {{ now | date "2006-01-02" }}`, 0o644)
+
+	render := func(args ...string) (string, error) {
+		configs, _ := parseRenderFlags(t, args...)
+
+		var buf bytes.Buffer
+		err := command(&buf, *configs)
+		return buf.String(), err
+	}
+
+	data := filepath.Join(dir, "templates", "data.txt")
+	first, err := render("--hermetic", "-e", env, "-f", tpl, "--set", "data="+data, "--seed", "7", "--clock", "2024-05-01T00:00:00Z")
+	if err != nil {
+		t.Fatalf("command() unexpected error: %v", err)
+	}
+
+	second, err := render("--hermetic", "-e", env, "-f", tpl, "--set", "data="+data, "--seed", "7", "--clock", "2024-05-01T00:00:00Z")
+	if err != nil {
+		t.Fatalf("command() unexpected error: %v", err)
+	}
+
+	if first != second {
+		t.Errorf("hermetic renders differ: %q and %q", first, second)
+	}
+
+	if expected := "app none  data "; !strings.HasPrefix(first, expected) {
+		t.Errorf("command() = %q, want it to start with %q", first, expected)
+	}
+
+	if expected := " 2024-05-01"; !strings.HasSuffix(first, expected) {
+		t.Errorf("command() = %q, want it to end with %q", first, expected)
+	}
+
+	if _, err := render("--hermetic", "-f", tpl, "--set", "data="+filepath.Join(dir, "secret.txt")); err == nil {
+		t.Error("command() expected an error reading a file outside of the template directory")
+	}
+
+	var missing *missingArgError
+	if _, err := render("--clock", "0", "-f", tpl); !errors.As(err, &missing) {
+		t.Errorf("command() error = %v, want a missing --hermetic error", err)
+	}
+}
+
+func TestHermeticDotenvValues(t *testing.T) {
+	t.Setenv("TGEN_TEST_HOST", "from-os")
+
+	dir := t.TempDir()
+	env := filepath.Join(dir, "build.env")
+	values := filepath.Join(dir, "values.env")
+	tpl := filepath.Join(dir, "values.tpl")
+	writeTestFile(t, env, "NAME=app\n", 0o644)
+	writeTestFile(t, values, "host=${TGEN_TEST_HOST:-none}\nname=${NAME}\n", 0o644)
+	writeTestFile(t, tpl, `{{ .host }} {{ .name }}`, 0o644)
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{name: "host environment", args: []string{"-e", env, "-v", values, "-f", tpl}, expected: "from-os app"},
+		{name: "hermetic", args: []string{"--hermetic", "-e", env, "-v", values, "-f", tpl}, expected: "none app"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configs, _ := parseRenderFlags(t, tt.args...)
+
+			var buf bytes.Buffer
+			if err := command(&buf, *configs); err != nil {
+				t.Fatalf("command() unexpected error: %v", err)
+			}
+
+			if got := buf.String(); got != tt.expected {
+				t.Errorf("command() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseClock(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected time.Time
+		wantErr  bool
+	}{
+		{input: "", expected: time.Unix(0, 0).UTC()},
+		{input: "1714521600", expected: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{input: "2024-05-01T02:00:00+02:00", expected: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{input: "yesterday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseClock(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseClock() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && !got.Equal(tt.expected) {
+				t.Errorf("parseClock() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
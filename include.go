@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/patrickdappollonio/tgen/tfuncs"
+)
+
+// helperTemplate is a template parsed into the same set as the main one, so
+// the templates it defines can be used from it
+type helperTemplate struct {
+	name    string
+	content string
+}
+
+// isHelperFile reports whether a file in directory mode is a helper library,
+// following Helm's convention of "_*.tpl" files
+func isHelperFile(path string) bool {
+	base := filepath.Base(path)
+	return strings.HasPrefix(base, "_") && strings.HasSuffix(base, ".tpl")
+}
+
+// addHelper adds a helper template to be parsed alongside the main template
+func (t *tgen) addHelper(name, content string) {
+	t.helpers = append(t.helpers, helperTemplate{name: name, content: content})
+}
+
+// loadIncludes loads the helper templates at path. If path is a directory,
+// every text file within it, recursively, is loaded in lexical order.
+func (t *tgen) loadIncludes(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		bf, err := tfuncs.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		t.addHelper(path, bf)
+		return nil
+	}
+
+	return filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		contents, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+
+		// Binary files can't hold template definitions
+		if bytes.IndexByte(contents, 0) >= 0 {
+			return nil
+		}
+
+		t.addHelper(p, string(contents))
+		return nil
+	})
+}
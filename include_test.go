@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testHelpers = `{{- define "labels" -}}
+app: {{ .name }}
+tier: {{ .tier }}
+{{- end -}}`
+
+func TestRenderWithIncludes(t *testing.T) {
+	dir := t.TempDir()
+	helpers := filepath.Join(dir, "helpers", "_labels.tpl")
+	writeTestFile(t, helpers, testHelpers, 0o644)
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		wantErr  string
+	}{
+		{
+			name:     "template action",
+			template: `{{ template "labels" . }}`,
+			want:     "app: web\ntier: front",
+		},
+		{
+			name:     "include piped into nindent",
+			template: "labels:{{ include \"labels\" . | nindent 2 }}",
+			want:     "labels:\n  app: web\n  tier: front",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Helpers are loaded from the directory they live in
+			tg := &tgen{yamlValues: map[string]any{"name": "web", "tier": "front"}}
+			if err := tg.loadIncludes(filepath.Dir(helpers)); err != nil {
+				t.Fatalf("loadIncludes() unexpected error: %v", err)
+			}
+
+			tg.setTemplate("main.txt", tt.template)
+
+			var buf bytes.Buffer
+			if err := tg.render(&buf); err != nil {
+				t.Fatalf("render() unexpected error: %v", err)
+			}
+
+			if buf.String() != tt.want {
+				t.Errorf("render() = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestIncludeErrorsPointAtHelper(t *testing.T) {
+	dir := t.TempDir()
+	helper := filepath.Join(dir, "_broken.tpl")
+	writeTestFile(t, helper, "line one\n{{ define \"x\" }}{{ .name ", 0o644)
+
+	tg := &tgen{}
+	if err := tg.loadIncludes(helper); err != nil {
+		t.Fatalf("loadIncludes() unexpected error: %v", err)
+	}
+
+	tg.setTemplate("main.txt", `{{ template "x" . }}`)
+
+	err := tg.render(&bytes.Buffer{})
+	if err == nil {
+		t.Fatal("render() expected error but got none")
+	}
+
+	if want := helper + ":2:"; !strings.Contains(err.Error(), want) {
+		t.Errorf("render() error = %q, want it to mention %q", err.Error(), want)
+	}
+}
+
+func TestIncludeRecursionLimit(t *testing.T) {
+	tg := &tgen{}
+	tg.setTemplate("main.txt", `{{ define "loop" }}{{ include "loop" . }}{{ end }}{{ include "loop" . }}`)
+
+	err := tg.render(&bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "maximum include depth") {
+		t.Errorf("render() error = %v, want maximum include depth error", err)
+	}
+}
+
+func TestRenderDirectoryHelpers(t *testing.T) {
+	input := t.TempDir()
+	output := t.TempDir()
+
+	writeTestFile(t, filepath.Join(input, "_helpers.tpl"), testHelpers, 0o644)
+	writeTestFile(t, filepath.Join(input, "nested", "app.yaml"), `{{ include "labels" . }}`, 0o644)
+
+	tg := &tgen{yamlValues: map[string]any{"name": "api", "tier": "back"}}
+	if err := tg.renderDirectory(input, output); err != nil {
+		t.Fatalf("renderDirectory() unexpected error: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(output, "nested", "app.yaml"))
+	if err != nil {
+		t.Fatalf("unable to read rendered file: %v", err)
+	}
+
+	if string(got) != "app: api\ntier: back" {
+		t.Errorf("rendered content = %q", got)
+	}
+
+	if _, err := os.Stat(filepath.Join(output, "_helpers.tpl")); !os.IsNotExist(err) {
+		t.Errorf("helper file was written to the output directory")
+	}
+}
+
+func TestLintWithHelpers(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "_helpers.tpl"), testHelpers+"\n{{ define \"broken\" }}{{ nope }}{{ end }}", 0o644)
+	writeTestFile(t, filepath.Join(dir, "app.yaml"), `{{ template "labels" . }}{{ include "labels" . | nindent 2 }}`, 0o644)
+
+	var buf bytes.Buffer
+	err := lintCommand(&buf, []string{dir}, lintOptions{format: "text"})
+	if err == nil {
+		t.Fatal("lintCommand() expected error but got none")
+	}
+
+	// Only the unknown function inside the helper itself is reported
+	want := filepath.Join(dir, "_helpers.tpl") + ":5:25: unknown-function"
+	if !strings.HasPrefix(buf.String(), want) || strings.Count(buf.String(), "\n") != 1 {
+		t.Errorf("lintCommand() output = %q, want a single finding starting with %q", buf.String(), want)
+	}
+}
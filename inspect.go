@@ -0,0 +1,380 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"text/template/parse"
+
+	"github.com/patrickdappollonio/tgen/render"
+	"github.com/patrickdappollonio/tgen/tfuncs"
+	"github.com/spf13/cobra"
+)
+
+// Kinds of variable references found in a template
+const (
+	refValue    = "value"
+	refEnv      = "env"
+	refRequired = "required"
+)
+
+// Status of a variable reference against the loaded values and environment
+const (
+	refFound   = "found"
+	refMissing = "missing"
+	refUnknown = "unknown"
+)
+
+// variableRef is a value path or environment variable referenced by a template
+type variableRef struct {
+	Kind      string   `json:"kind"`
+	Name      string   `json:"name"`
+	Status    string   `json:"status"`
+	Locations []string `json:"locations"`
+
+	// path is the parsed form of Name for value references, nil when
+	// the reference can't be resolved from the root of the values
+	path []string
+}
+
+func newInspectCommand() *cobra.Command {
+	inspect := &cobra.Command{
+		Use:   "inspect",
+		Short: "inspect templates without rendering them",
+	}
+
+	var configs conf
+	var format string
+
+	vars := &cobra.Command{
+		Use:   "vars",
+		Short: "list the values and environment variables a template references",
+		Long: "List every values path and environment variable referenced by a template, and whether\n" +
+			"they are set in the loaded values files, --set flags, environment file or OS environment.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := applyProjectConfig(cmd.Flags(), ""); err != nil {
+				return err
+			}
+
+			return inspectVarsCommand(cmd.OutOrStdout(), configs, format)
+		},
+	}
+
+	addInputFlags(vars.Flags(), &configs)
+	vars.Flags().StringVar(&format, "format", "table", `output format, either "table" or "json"`)
+	vars.Flags().SortFlags = false
+
+	inspect.AddCommand(vars)
+	return inspect
+}
+
+func inspectVarsCommand(w io.Writer, c conf, format string) error {
+	if format != "table" && format != "json" {
+		return fmt.Errorf("unknown inspect format %q: valid options are \"table\" or \"json\"", format)
+	}
+
+	if c.templateFilePath != "" && c.stdinTemplateFile != "" {
+		return &conflictingArgsError{"file", "execute"}
+	}
+
+	tg, err := loadInputs(c)
+	if err != nil {
+		return err
+	}
+
+	if tg.templateFileContent == "" {
+		return errors.New("no template to inspect: use --file or --execute")
+	}
+
+	refs, err := tg.inspectVariables()
+	if err != nil {
+		return err
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(refs)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KIND\tNAME\tSTATUS\tLOCATIONS")
+	for _, r := range refs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Kind, r.Name, r.Status, strings.Join(r.Locations, ", "))
+	}
+
+	return tw.Flush()
+}
+
+// inspectVariables parses the loaded template and returns every variable it
+// references, with its status against the loaded values and environment
+func (t *tgen) inspectVariables() ([]variableRef, error) {
+	tree := parse.New(t.templateFileName)
+	tree.Mode = parse.SkipFuncCheck
+	treeSet := make(map[string]*parse.Tree)
+
+	if _, err := tree.Parse(t.templateFileContent, t.preDelimiter, t.postDelimiter, treeSet); err != nil {
+		return nil, fmt.Errorf("unable to parse template file %q: %s", t.templateFileName, err.Error())
+	}
+
+	ins := &inspector{refs: make(map[string]*variableRef), env: t.environment(), content: t.templateFileContent}
+
+	names := make([]string, 0, len(treeSet))
+	for n := range treeSet {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	for _, n := range names {
+		ins.tree = treeSet[n]
+
+		// Only the main template is known to receive the root values,
+		// defined templates can be called with any context
+		if n == t.templateFileName {
+			ins.dot = []string{}
+		} else {
+			ins.dot = nil
+		}
+
+		ins.walk(ins.tree.Root)
+	}
+
+	refs := make([]variableRef, 0, len(ins.refs))
+	for _, r := range ins.refs {
+		r.Status = t.variableStatus(r)
+		refs = append(refs, *r)
+	}
+
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].Kind != refs[j].Kind {
+			return refs[i].Kind < refs[j].Kind
+		}
+
+		return refs[i].Name < refs[j].Name
+	})
+
+	return refs, nil
+}
+
+// variableStatus checks whether a reference is set in the loaded sources
+func (t *tgen) variableStatus(r *variableRef) string {
+	if r.Kind == refEnv {
+		if _, found := t.environment().Lookup(r.Name); found {
+			return refFound
+		}
+
+		return refMissing
+	}
+
+	// Elements of a list can't be checked without rendering
+	if r.path == nil || slices.Contains(r.path, "[]") {
+		return refUnknown
+	}
+
+	var current any = t.yamlValues
+	for _, part := range r.path {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return refMissing
+		}
+
+		if current, ok = m[part]; !ok {
+			return refMissing
+		}
+	}
+
+	return refFound
+}
+
+// inspector walks a template parse tree collecting variable references. It
+// keeps track of what "." refers to, so fields used inside "with" and
+// "range" blocks can be reported with their full path.
+type inspector struct {
+	tree *parse.Tree
+	refs map[string]*variableRef
+
+	// content is the source of the template, used to locate references
+	content string
+
+	// env names environment variables the way the env functions do
+	env tfuncs.Environment
+
+	// dot is the values path "." currently points to, or nil when it
+	// can't be determined statically
+	dot []string
+}
+
+func (ins *inspector) add(kind, name string, path []string, node parse.Node) {
+	context, _ := ins.tree.ErrorContext(node)
+	file, line, col := render.LocateExpression(ins.content, context)
+	location := fmt.Sprintf("%s:%d:%d", file, line, col)
+
+	key := kind + "\x00" + name
+	ref, found := ins.refs[key]
+	if !found {
+		ref = &variableRef{Kind: kind, Name: name, path: path, Locations: []string{}}
+		ins.refs[key] = ref
+	}
+
+	ref.Locations = append(ref.Locations, location)
+}
+
+// formatPath renders a values path as it would be written in a template
+func formatPath(path []string) string {
+	var sb strings.Builder
+	for _, p := range path {
+		if p == "[]" {
+			sb.WriteString("[]")
+			continue
+		}
+
+		sb.WriteString(".")
+		sb.WriteString(p)
+	}
+
+	if sb.Len() == 0 {
+		return "."
+	}
+
+	return sb.String()
+}
+
+// resolve returns the full values path for a node that evaluates to a
+// field chain, and the name to display for it. The path is nil if the node
+// doesn't start from a known point of the values.
+func (ins *inspector) resolve(node parse.Node) ([]string, string, bool) {
+	switch n := node.(type) {
+	case *parse.FieldNode:
+		if ins.dot == nil {
+			return nil, formatPath(n.Ident), true
+		}
+
+		path := append(append([]string{}, ins.dot...), n.Ident...)
+		return path, formatPath(path), true
+	case *parse.VariableNode:
+		// Only "$" is known to point to the root values
+		if n.Ident[0] != "$" || len(n.Ident) == 1 {
+			return nil, "", false
+		}
+
+		path := append([]string{}, n.Ident[1:]...)
+		return path, formatPath(path), true
+	case *parse.DotNode:
+		if ins.dot == nil {
+			return nil, "", false
+		}
+
+		return ins.dot, formatPath(ins.dot), true
+	case *parse.PipeNode:
+		// A pipeline made of a single field, like "(.Values.foo)"
+		if n != nil && len(n.Cmds) == 1 && len(n.Cmds[0].Args) == 1 {
+			return ins.resolve(n.Cmds[0].Args[0])
+		}
+	}
+
+	return nil, "", false
+}
+
+func (ins *inspector) walk(node parse.Node) {
+	switch n := node.(type) {
+	case nil:
+		return
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+
+		for _, child := range n.Nodes {
+			ins.walk(child)
+		}
+	case *parse.ActionNode:
+		ins.walkPipe(n.Pipe)
+	case *parse.IfNode:
+		ins.walkPipe(n.Pipe)
+		ins.walk(n.List)
+		ins.walk(n.ElseList)
+	case *parse.WithNode:
+		ins.walkScoped(n.Pipe, n.List, n.ElseList, false)
+	case *parse.RangeNode:
+		ins.walkScoped(n.Pipe, n.List, n.ElseList, true)
+	case *parse.TemplateNode:
+		ins.walkPipe(n.Pipe)
+	}
+}
+
+// walkScoped walks a "with" or "range" block, where "." changes to the
+// value of the pipeline (or each of its elements, for "range") within list
+func (ins *inspector) walkScoped(pipe *parse.PipeNode, list, elseList *parse.ListNode, isRange bool) {
+	ins.walkPipe(pipe)
+
+	outer := ins.dot
+
+	var inner []string
+	if path, _, ok := ins.resolve(pipe); ok && path != nil {
+		inner = append([]string{}, path...)
+		if isRange {
+			inner = append(inner, "[]")
+		}
+	}
+
+	ins.dot = inner
+	ins.walk(list)
+	ins.dot = outer
+	ins.walk(elseList)
+}
+
+func (ins *inspector) walkPipe(pipe *parse.PipeNode) {
+	if pipe == nil {
+		return
+	}
+
+	for i, cmd := range pipe.Cmds {
+		for _, arg := range cmd.Args {
+			switch a := arg.(type) {
+			case *parse.PipeNode:
+				ins.walkPipe(a)
+			case *parse.ChainNode:
+				if p, ok := a.Node.(*parse.PipeNode); ok {
+					ins.walkPipe(p)
+				}
+			default:
+				if path, name, ok := ins.resolve(arg); ok && len(name) > 1 {
+					ins.add(refValue, name, path, arg)
+				}
+			}
+		}
+
+		ident, ok := cmd.Args[0].(*parse.IdentifierNode)
+		if !ok {
+			continue
+		}
+
+		switch ident.Ident {
+		case "env", "envdefault":
+			if len(cmd.Args) > 1 {
+				if s, ok := cmd.Args[1].(*parse.StringNode); ok {
+					ins.add(refEnv, ins.env.Key(s.Text), nil, s)
+				}
+			}
+		case "required":
+			// The checked value is either the second argument, or the
+			// result of the previous command when piped
+			var checked parse.Node
+			if len(cmd.Args) > 2 {
+				checked = cmd.Args[2]
+			} else if i > 0 && len(pipe.Cmds[i-1].Args) == 1 {
+				checked = pipe.Cmds[i-1].Args[0]
+			}
+
+			if path, name, ok := ins.resolve(checked); ok {
+				ins.add(refRequired, name, path, ident)
+			}
+		}
+	}
+}
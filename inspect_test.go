@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/patrickdappollonio/tgen/internal/setflags"
+)
+
+func TestInspectVariables(t *testing.T) {
+	t.Setenv("TGEN_INSPECT_SET", "1")
+
+	tg := &tgen{
+		envValues: map[string]string{"FROM_FILE": "yes"},
+	}
+
+	tg.mergeValues(map[string]any{
+		"db":   map[string]any{"host": "localhost"},
+		"name": "app",
+	})
+
+	tg.setTemplate("tpl", `{{ .Values.db.host }}:{{ .Values.db.port }} {{ .name }}
+{{ with .Values.cache }}{{ .host }}{{ end }}{{ range .items }}{{ .id }}{{ end }}
+{{ env "tgen_inspect_set" }}{{ envdefault "FROM_FILE" "x" }}{{ env "NOPE" }}
+{{ required "msg" .Values.secret }}{{ .token | required "msg" }}{{ $.db.host }}
+{{ define "helper" }}{{ .relative }}{{ end }}`)
+
+	refs, err := tg.inspectVariables()
+	if err != nil {
+		t.Fatalf("inspectVariables() unexpected error: %v", err)
+	}
+
+	got := make(map[string]string)
+	for _, r := range refs {
+		got[r.Kind+" "+r.Name] = r.Status
+	}
+
+	expected := map[string]string{
+		"value .Values.db.host":    refFound,
+		"value .Values.db.port":    refMissing,
+		"value .name":              refFound,
+		"value .Values.cache":      refMissing,
+		"value .Values.cache.host": refMissing,
+		"value .items":             refMissing,
+		"value .items[].id":        refUnknown,
+		"value .Values.secret":     refMissing,
+		"value .token":             refMissing,
+		"value .db.host":           refFound,
+		"value .relative":          refUnknown,
+		"env TGEN_INSPECT_SET":     refFound,
+		"env FROM_FILE":            refFound,
+		"env NOPE":                 refMissing,
+		"required .Values.secret":  refMissing,
+		"required .token":          refMissing,
+	}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("inspectVariables() = %v, want %v", got, expected)
+	}
+	// Locations are 1-based and point at the start of the reference, like
+	// the ones reported by lint and rendering errors
+	locations := make(map[string][]string)
+	for _, r := range refs {
+		locations[r.Kind+" "+r.Name] = r.Locations
+	}
+
+	expectedLocations := map[string][]string{
+		"value .Values.db.host": {"tpl:1:4"},
+		"value .Values.db.port": {"tpl:1:26"},
+		"value .name":           {"tpl:1:48"},
+		"value .db.host":        {"tpl:4:68"},
+		"env NOPE":              {"tpl:3:68"},
+	}
+
+	for ref, expected := range expectedLocations {
+		if !reflect.DeepEqual(locations[ref], expected) {
+			t.Errorf("locations of %s = %q, want %q", ref, locations[ref], expected)
+		}
+	}
+}
+
+func TestInspectVarsCommandJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := inspectVarsCommand(&buf, conf{stdinTemplateFile: "{{ .a }}{{ .a }}", setFlags: []setflags.Flag{{Kind: setflags.KindSet, Value: "a=1"}}}, "json"); err != nil {
+		t.Fatalf("inspectVarsCommand() unexpected error: %v", err)
+	}
+
+	var refs []variableRef
+	if err := json.Unmarshal(buf.Bytes(), &refs); err != nil {
+		t.Fatalf("inspectVarsCommand() produced invalid JSON: %v", err)
+	}
+
+	if len(refs) != 1 || refs[0].Name != ".a" || refs[0].Status != refFound || len(refs[0].Locations) != 2 {
+		t.Errorf("inspectVarsCommand() = %+v", refs)
+	}
+}
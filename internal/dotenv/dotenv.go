@@ -0,0 +1,410 @@
+// Package dotenv parses environment files in the format used by
+// docker-compose and most dotenv libraries:
+//
+//	# comments and blank lines are ignored
+//	export NAME=value        # "export" prefixes are allowed
+//	LITERAL='no ${expansion} or \escapes'
+//	QUOTED="escapes\tand ${NAME} expansion"
+//	MULTILINE="first line
+//	second line"
+//	DEFAULTED=${UNSET:-fallback}
+//
+// Variables referenced as $NAME or ${NAME} in unquoted and double-quoted
+// values are expanded, using the keys defined earlier in the file first.
+package dotenv
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Error is a syntax or expansion error, with the line where the offending
+// key is defined
+type Error struct {
+	Line    int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// Entry is a key/value pair, with the line where the key is defined
+type Entry struct {
+	Key   string
+	Value string
+	Line  int
+}
+
+// reKey matches valid keys
+var reKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// Parse parses an environment file into its key/value pairs, in the order
+// they're defined. Keys defined more than once appear once per definition,
+// so applying the entries in order lets the last one win. Variables that
+// aren't defined earlier in the file are resolved with lookup, which can be
+// nil, and expand to an empty string if they're not found.
+func Parse(data []byte, lookup func(key string) (string, bool)) ([]Entry, error) {
+	src := strings.TrimPrefix(string(data), "\ufeff")
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+
+	p := &parser{
+		src:    src,
+		line:   1,
+		lookup: lookup,
+		values: make(map[string]string),
+	}
+
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+
+	return p.entries, nil
+}
+
+type parser struct {
+	src  string
+	pos  int
+	line int
+
+	// keyLine is the line where the key being parsed is defined
+	keyLine int
+
+	lookup  func(key string) (string, bool)
+	values  map[string]string
+	entries []Entry
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return &Error{Line: p.keyLine, Message: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *parser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+
+	return p.src[p.pos]
+}
+
+func (p *parser) skipSpace() {
+	for !p.eof() && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// skipLine moves past the end of the current line
+func (p *parser) skipLine() {
+	for !p.eof() && p.src[p.pos] != '\n' {
+		p.pos++
+	}
+
+	if !p.eof() {
+		p.pos++
+		p.line++
+	}
+}
+
+func (p *parser) parse() error {
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return nil
+		}
+
+		p.keyLine = p.line
+
+		switch p.peek() {
+		case '\n', '#':
+			p.skipLine()
+			continue
+		}
+
+		key, err := p.parseKey()
+		if err != nil {
+			return err
+		}
+
+		value, err := p.parseValue()
+		if err != nil {
+			return err
+		}
+
+		p.values[key] = value
+		p.entries = append(p.entries, Entry{Key: key, Value: value, Line: p.keyLine})
+	}
+}
+
+func (p *parser) parseKey() (string, error) {
+	if strings.HasPrefix(p.src[p.pos:], "export") {
+		if next := p.src[p.pos+len("export"):]; next != "" && (next[0] == ' ' || next[0] == '\t') {
+			p.pos += len("export")
+			p.skipSpace()
+		}
+	}
+
+	start := p.pos
+	for !p.eof() && !strings.ContainsRune("= \t\n", rune(p.peek())) {
+		p.pos++
+	}
+
+	key := p.src[start:p.pos]
+	p.skipSpace()
+
+	if p.peek() != '=' {
+		if key == "" {
+			return "", p.errorf("expected a key")
+		}
+
+		return "", p.errorf("key=value separator not found after %q", key)
+	}
+
+	if key == "" {
+		return "", p.errorf("empty key before \"=\"")
+	}
+
+	if !reKey.MatchString(key) {
+		return "", p.errorf("invalid key %q: keys must start with a letter or underscore, followed by letters, digits, underscores, dots or dashes", key)
+	}
+
+	p.pos++
+	p.skipSpace()
+	return key, nil
+}
+
+func (p *parser) parseValue() (string, error) {
+	switch p.peek() {
+	case '\'':
+		raw, err := p.quoted('\'')
+		if err != nil {
+			return "", err
+		}
+
+		return raw, p.endOfValue()
+	case '"':
+		raw, err := p.quoted('"')
+		if err != nil {
+			return "", err
+		}
+
+		value, err := p.expand(raw, true)
+		if err != nil {
+			return "", err
+		}
+
+		return value, p.endOfValue()
+	}
+
+	start := p.pos
+	for !p.eof() && p.peek() != '\n' {
+		// A "#" starts a comment only after whitespace
+		if p.peek() == '#' && (p.pos == start || p.src[p.pos-1] == ' ' || p.src[p.pos-1] == '\t') {
+			break
+		}
+
+		p.pos++
+	}
+
+	raw := strings.TrimSpace(p.src[start:p.pos])
+	p.skipLine()
+
+	return p.expand(raw, false)
+}
+
+// quoted returns the raw contents of a value enclosed in quote, which can
+// span several lines. Within double quotes, escaped quotes don't end it.
+func (p *parser) quoted(quote byte) (string, error) {
+	p.pos++
+
+	start := p.pos
+	for !p.eof() {
+		c := p.src[p.pos]
+
+		switch {
+		case c == quote:
+			raw := p.src[start:p.pos]
+			p.pos++
+			return raw, nil
+		case c == '\\' && quote == '"' && p.pos+1 < len(p.src):
+			if p.src[p.pos+1] == '\n' {
+				p.line++
+			}
+
+			p.pos += 2
+			continue
+		case c == '\n':
+			p.line++
+		}
+
+		p.pos++
+	}
+
+	if quote == '\'' {
+		return "", p.errorf("unterminated single-quoted value")
+	}
+
+	return "", p.errorf("unterminated double-quoted value")
+}
+
+// endOfValue consumes the rest of the line after a quoted value, which can
+// only hold whitespace and a comment
+func (p *parser) endOfValue() error {
+	p.skipSpace()
+
+	if !p.eof() && p.peek() != '\n' && p.peek() != '#' {
+		return p.errorf("unexpected %q after quoted value", p.peek())
+	}
+
+	p.skipLine()
+	return nil
+}
+
+// expand resolves the variable references in s and, when escapes is set,
+// its escape sequences
+func (p *parser) expand(s string, escapes bool) (string, error) {
+	var sb strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		switch {
+		case escapes && c == '\\' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			case 'r':
+				sb.WriteByte('\r')
+			case '"', '\\', '$':
+				sb.WriteByte(s[i])
+			default:
+				sb.WriteByte('\\')
+				sb.WriteByte(s[i])
+			}
+		case c == '$' && i+1 < len(s) && s[i+1] == '{':
+			end := closingBrace(s, i+2)
+			if end < 0 {
+				return "", p.errorf("unterminated variable reference %q", s[i:])
+			}
+
+			value, err := p.resolve(s[i+2 : end])
+			if err != nil {
+				return "", err
+			}
+
+			sb.WriteString(value)
+			i = end
+		case c == '$' && i+1 < len(s) && isNameStart(s[i+1]):
+			j := i + 1
+			for j < len(s) && isNameChar(s[j]) {
+				j++
+			}
+
+			value, _ := p.get(s[i+1 : j])
+			sb.WriteString(value)
+			i = j - 1
+		default:
+			sb.WriteByte(c)
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// closingBrace returns the index of the "}" closing a reference whose
+// contents start at start, allowing nested references in defaults
+func closingBrace(s string, start int) int {
+	depth := 1
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// resolve evaluates the contents of a ${...} reference. Besides a plain
+// name, it supports the shell forms ${NAME:-default}, ${NAME-default},
+// ${NAME:?error} and ${NAME?error}.
+func (p *parser) resolve(expr string) (string, error) {
+	n := 0
+	for n < len(expr) && isNameChar(expr[n]) {
+		n++
+	}
+
+	name, op := expr[:n], expr[n:]
+	if name == "" || !isNameStart(name[0]) {
+		return "", p.errorf("invalid variable reference \"${%s}\"", expr)
+	}
+
+	value, found := p.get(name)
+
+	switch {
+	case op == "":
+		return value, nil
+	case strings.HasPrefix(op, ":-"):
+		if !found || value == "" {
+			return p.expand(op[2:], false)
+		}
+	case strings.HasPrefix(op, "-"):
+		if !found {
+			return p.expand(op[1:], false)
+		}
+	case strings.HasPrefix(op, ":?"):
+		if !found || value == "" {
+			return "", p.requiredError(name, op[2:])
+		}
+	case strings.HasPrefix(op, "?"):
+		if !found {
+			return "", p.requiredError(name, op[1:])
+		}
+	default:
+		return "", p.errorf("invalid variable reference \"${%s}\"", expr)
+	}
+
+	return value, nil
+}
+
+func (p *parser) requiredError(name, message string) error {
+	if message == "" {
+		message = "required variable is not set"
+	}
+
+	return p.errorf("%s: %s", name, message)
+}
+
+// get returns the value of a variable defined earlier in the file, or from
+// lookup otherwise
+func (p *parser) get(name string) (string, bool) {
+	if value, found := p.values[name]; found {
+		return value, true
+	}
+
+	if p.lookup != nil {
+		return p.lookup(name)
+	}
+
+	return "", false
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || c >= '0' && c <= '9'
+}
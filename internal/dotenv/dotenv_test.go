@@ -0,0 +1,163 @@
+package dotenv
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	env := map[string]string{"HOME": "/home/tgen", "EMPTY": ""}
+	lookup := func(key string) (string, bool) {
+		v, found := env[key]
+		return v, found
+	}
+
+	tests := []struct {
+		name     string
+		content  string
+		expected map[string]string
+	}{
+		{
+			name:     "export prefix and spaces around the separator",
+			content:  "export NAME = value\nexport\tOTHER=1\nexported=2",
+			expected: map[string]string{"NAME": "value", "OTHER": "1", "exported": "2"},
+		},
+		{
+			name:     "empty values are kept",
+			content:  "EMPTY=\nQUOTED=\"\"\nSINGLE=''",
+			expected: map[string]string{"EMPTY": "", "QUOTED": "", "SINGLE": ""},
+		},
+		{
+			name:     "inline comments",
+			content:  "A=value # comment\nB=value#not-a-comment\nC=\"quoted # not a comment\" # comment\nD= # only a comment",
+			expected: map[string]string{"A": "value", "B": "value#not-a-comment", "C": "quoted # not a comment", "D": ""},
+		},
+		{
+			name:     "single quotes are literal",
+			content:  `A='${HOME}\n "x"'`,
+			expected: map[string]string{"A": `${HOME}\n "x"`},
+		},
+		{
+			name:     "double quote escapes",
+			content:  `A="tab\there\nnew line \"quoted\" \\ \$HOME \q"`,
+			expected: map[string]string{"A": "tab\there\nnew line \"quoted\" \\ $HOME \\q"},
+		},
+		{
+			name:     "multi-line values",
+			content:  "KEY=\"-----BEGIN KEY-----\nabc\n-----END KEY-----\"\nLITERAL='line 1\nline 2'\nAFTER=1",
+			expected: map[string]string{"KEY": "-----BEGIN KEY-----\nabc\n-----END KEY-----", "LITERAL": "line 1\nline 2", "AFTER": "1"},
+		},
+		{
+			name: "interpolation",
+			content: "NAME=tgen\n" +
+				"A=${NAME}-$NAME\n" +
+				"B=\"${HOME}/.config/${NAME}\"\n" +
+				"C=${UNSET:-fallback}\n" +
+				"D=${EMPTY:-fallback}\n" +
+				"E=${EMPTY-fallback}\n" +
+				"F=${UNSET:-${NAME}}\n" +
+				"G=${UNSET}\n" +
+				"H=cost: $5",
+			expected: map[string]string{
+				"NAME": "tgen",
+				"A":    "tgen-tgen",
+				"B":    "/home/tgen/.config/tgen",
+				"C":    "fallback",
+				"D":    "fallback",
+				"E":    "",
+				"F":    "tgen",
+				"G":    "",
+				"H":    "cost: $5",
+			},
+		},
+		{
+			name:     "earlier keys take precedence over the environment",
+			content:  "HOME=/custom\nCONFIG=${HOME}/config",
+			expected: map[string]string{"HOME": "/custom", "CONFIG": "/custom/config"},
+		},
+		{
+			name:     "windows line endings",
+			content:  "A=1\r\nB=\"2\"\r\n",
+			expected: map[string]string{"A": "1", "B": "2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := Parse([]byte(tt.content), lookup)
+			if err != nil {
+				t.Fatalf("Parse() unexpected error: %v", err)
+			}
+
+			got := make(map[string]string, len(entries))
+			for _, entry := range entries {
+				got[entry.Key] = entry.Value
+			}
+
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Parse() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseOrder(t *testing.T) {
+	content := "# duplicates are kept\nFOO=upper\nfoo=lower\n\nFOO=\"again\n${foo}\"\nBAR=$FOO"
+
+	got, err := Parse([]byte(content), nil)
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+
+	expected := []Entry{
+		{Key: "FOO", Value: "upper", Line: 2},
+		{Key: "foo", Value: "lower", Line: 3},
+		{Key: "FOO", Value: "again\nlower", Line: 5},
+		{Key: "BAR", Value: "again\nlower", Line: 7},
+	}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Parse() = %+v, want %+v", got, expected)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		line    int
+		message string
+	}{
+		{name: "missing separator", content: "A=1\n\nB", line: 3, message: "separator not found"},
+		{name: "empty key", content: "=1", line: 1, message: "empty key"},
+		{name: "invalid key", content: "1A=1", line: 1, message: "invalid key"},
+		{name: "unterminated double quotes", content: "A=1\nB=\"abc\nC=1", line: 2, message: "unterminated double-quoted value"},
+		{name: "unterminated single quotes", content: "A='abc", line: 1, message: "unterminated single-quoted value"},
+		{name: "text after quotes", content: "A=\"abc\" def", line: 1, message: "after quoted value"},
+		{name: "error after multi-line value", content: "A=\"a\nb\"\nB=\"x\" y", line: 3, message: "after quoted value"},
+		{name: "unterminated reference", content: "A=${B", line: 1, message: "unterminated variable reference"},
+		{name: "invalid reference", content: "A=${1B}", line: 1, message: "invalid variable reference"},
+		{name: "required variable", content: "A=${UNSET:?must be set}", line: 1, message: "UNSET: must be set"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.content), nil)
+
+			var perr *Error
+			if !errors.As(err, &perr) {
+				t.Fatalf("Parse() error = %v, want *Error", err)
+			}
+
+			if perr.Line != tt.line {
+				t.Errorf("Parse() error line = %d, want %d", perr.Line, tt.line)
+			}
+
+			if !strings.Contains(perr.Message, tt.message) {
+				t.Errorf("Parse() error = %q, want it to contain %q", perr.Message, tt.message)
+			}
+		})
+	}
+}
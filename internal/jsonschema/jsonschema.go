@@ -0,0 +1,808 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Schema is a parsed JSON Schema document. It implements the validation
+// vocabulary of JSON Schema draft 2020-12, with the exception of the
+// "unevaluated*" keywords, "$dynamicRef" and references to other documents.
+// The "format" keyword is treated as an annotation, as the specification
+// allows by default.
+type Schema struct {
+	root  any
+	regex map[string]*regexp.Regexp
+
+	// refs counts the references being followed for each path while
+	// validating, to stop on references that loop without descending
+	// into the value
+	refs map[string]int
+}
+
+// Violation is a single validation failure at a given location of the
+// validated document
+type Violation struct {
+	// Path is the JSON pointer to the offending value, like "/db/port"
+	Path    string
+	Message string
+}
+
+func (v Violation) String() string {
+	path := v.Path
+	if path == "" {
+		path = "/"
+	}
+
+	return path + ": " + v.Message
+}
+
+// ValidationError is returned when a document doesn't match a schema, and
+// holds every violation found
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		lines = append(lines, v.String())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Parse parses a JSON Schema document
+func Parse(data []byte) (*Schema, error) {
+	var root any
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+
+	switch root.(type) {
+	case map[string]any, bool:
+	default:
+		return nil, fmt.Errorf("invalid JSON schema: root must be an object or a boolean")
+	}
+
+	return &Schema{root: root, regex: make(map[string]*regexp.Regexp), refs: make(map[string]int)}, nil
+}
+
+// Validate checks v against the schema. It returns a *ValidationError
+// listing every violation found, or nil if the document is valid.
+func (s *Schema) Validate(v any) error {
+	var violations []Violation
+	if err := s.validate(s.root, v, "", &violations); err != nil {
+		return err
+	}
+
+	if len(violations) > 0 {
+		return &ValidationError{Violations: violations}
+	}
+
+	return nil
+}
+
+// ApplyDefaults fills in every property missing from v, and from the objects
+// nested in it, with the "default" declared for it in the schema. Only
+// defaults declared through "properties", directly or behind "$ref", are
+// applied, since they're the only ones whose location is unambiguous.
+func (s *Schema) ApplyDefaults(v map[string]any) error {
+	return s.applyDefaults(s.root, v, 0)
+}
+
+// maxRefDepth limits how many references are followed, to avoid looping
+// forever on recursive schemas
+const maxRefDepth = 32
+
+func (s *Schema) applyDefaults(schema any, v map[string]any, depth int) error {
+	if depth > maxRefDepth {
+		return fmt.Errorf("too many nested references in schema")
+	}
+
+	obj, ok := schema.(map[string]any)
+	if !ok {
+		return nil
+	}
+
+	props, _ := obj["properties"].(map[string]any)
+	for _, name := range sortedKeys(props) {
+		if _, found := v[name]; !found {
+			def, found, err := s.defaultFor(props[name])
+			if err != nil {
+				return err
+			}
+
+			if !found {
+				continue
+			}
+
+			v[name] = deepCopy(def)
+		}
+
+		if nested, ok := v[name].(map[string]any); ok {
+			if err := s.applyDefaults(props[name], nested, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Properties declared in a referenced schema apply too
+	if ref, ok := obj["$ref"].(string); ok {
+		resolved, err := s.resolve(ref)
+		if err != nil {
+			return err
+		}
+
+		return s.applyDefaults(resolved, v, depth+1)
+	}
+
+	return nil
+}
+
+// defaultFor returns the default declared in schema, following "$ref"
+// when the schema doesn't declare one itself
+func (s *Schema) defaultFor(schema any) (any, bool, error) {
+	for range maxRefDepth {
+		obj, ok := schema.(map[string]any)
+		if !ok {
+			return nil, false, nil
+		}
+
+		if def, found := obj["default"]; found {
+			return def, true, nil
+		}
+
+		ref, ok := obj["$ref"].(string)
+		if !ok {
+			return nil, false, nil
+		}
+
+		resolved, err := s.resolve(ref)
+		if err != nil {
+			return nil, false, err
+		}
+
+		schema = resolved
+	}
+
+	return nil, false, fmt.Errorf("too many nested references in schema")
+}
+
+// resolve finds the schema pointed to by a local reference like
+// "#/$defs/port"
+func (s *Schema) resolve(ref string) (any, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("unsupported schema reference %q: only local references are allowed", ref)
+	}
+
+	pointer := strings.TrimPrefix(ref, "#")
+	current := s.root
+
+	if pointer == "" {
+		return current, nil
+	}
+
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("unsupported schema reference %q: anchors are not supported", ref)
+	}
+
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+
+		switch c := current.(type) {
+		case map[string]any:
+			next, found := c[token]
+			if !found {
+				return nil, fmt.Errorf("unable to resolve schema reference %q", ref)
+			}
+
+			current = next
+		case []any:
+			idx, err := strconv.Atoi(token)
+			if err != nil || idx < 0 || idx >= len(c) {
+				return nil, fmt.Errorf("unable to resolve schema reference %q", ref)
+			}
+
+			current = c[idx]
+		default:
+			return nil, fmt.Errorf("unable to resolve schema reference %q", ref)
+		}
+	}
+
+	return current, nil
+}
+
+func (s *Schema) regexp(pattern string) (*regexp.Regexp, error) {
+	if re, found := s.regex[pattern]; found {
+		return re, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q in schema: %w", pattern, err)
+	}
+
+	s.regex[pattern] = re
+	return re, nil
+}
+
+// isValid reports whether v matches schema, without collecting violations
+func (s *Schema) isValid(schema, v any, path string) (bool, error) {
+	var violations []Violation
+	if err := s.validate(schema, v, path, &violations); err != nil {
+		return false, err
+	}
+
+	return len(violations) == 0, nil
+}
+
+func (s *Schema) validate(schema, v any, path string, out *[]Violation) error {
+	fail := func(format string, args ...any) {
+		*out = append(*out, Violation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if allowed, ok := schema.(bool); ok {
+		if !allowed {
+			fail("value is not allowed")
+		}
+
+		return nil
+	}
+
+	obj, ok := schema.(map[string]any)
+	if !ok {
+		return fmt.Errorf("invalid schema at %q: expected an object or a boolean", path)
+	}
+
+	v = normalize(v)
+
+	if ref, ok := obj["$ref"].(string); ok {
+		resolved, err := s.resolve(ref)
+		if err != nil {
+			return err
+		}
+
+		// Recursive schemas are fine as long as each reference descends
+		// into the value, so only references followed for the same path
+		// are counted
+		if s.refs[path] >= maxRefDepth {
+			return fmt.Errorf("too many nested references in schema at %q", path)
+		}
+
+		s.refs[path]++
+		err = s.validate(resolved, v, path, out)
+		s.refs[path]--
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if t, found := obj["type"]; found {
+		var allowed []string
+		switch t := t.(type) {
+		case string:
+			allowed = []string{t}
+		case []any:
+			for _, item := range t {
+				if name, ok := item.(string); ok {
+					allowed = append(allowed, name)
+				}
+			}
+		}
+
+		if !matchesType(v, allowed) {
+			fail("expected %s, got %s", strings.Join(allowed, " or "), typeOf(v))
+			// Other keywords would only add noise on a wrong type
+			return nil
+		}
+	}
+
+	if enum, ok := obj["enum"].([]any); ok {
+		found := false
+		for _, candidate := range enum {
+			if equal(v, normalize(candidate)) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			fail("value must be one of %s", formatList(enum))
+		}
+	}
+
+	if c, found := obj["const"]; found && !equal(v, normalize(c)) {
+		fail("value must be %s", formatValue(c))
+	}
+
+	switch val := v.(type) {
+	case string:
+		s.validateString(obj, val, fail)
+	case float64:
+		validateNumber(obj, val, fail)
+	case map[string]any:
+		if err := s.validateObject(obj, val, path, out, fail); err != nil {
+			return err
+		}
+	case []any:
+		if err := s.validateArray(obj, val, path, out, fail); err != nil {
+			return err
+		}
+	}
+
+	return s.validateCombinators(obj, v, path, out, fail)
+}
+
+func (s *Schema) validateString(obj map[string]any, v string, fail func(string, ...any)) {
+	length := utf8.RuneCountInString(v)
+
+	if n, ok := number(obj["minLength"]); ok && float64(length) < n {
+		fail("string must be at least %v characters long", n)
+	}
+
+	if n, ok := number(obj["maxLength"]); ok && float64(length) > n {
+		fail("string must be at most %v characters long", n)
+	}
+
+	if pattern, ok := obj["pattern"].(string); ok {
+		re, err := s.regexp(pattern)
+		if err != nil {
+			fail("%s", err)
+		} else if !re.MatchString(v) {
+			fail("string must match pattern %q", pattern)
+		}
+	}
+}
+
+func validateNumber(obj map[string]any, v float64, fail func(string, ...any)) {
+	if n, ok := number(obj["minimum"]); ok && v < n {
+		fail("value must be greater than or equal to %v", n)
+	}
+
+	if n, ok := number(obj["maximum"]); ok && v > n {
+		fail("value must be less than or equal to %v", n)
+	}
+
+	if n, ok := number(obj["exclusiveMinimum"]); ok && v <= n {
+		fail("value must be greater than %v", n)
+	}
+
+	if n, ok := number(obj["exclusiveMaximum"]); ok && v >= n {
+		fail("value must be less than %v", n)
+	}
+
+	if n, ok := number(obj["multipleOf"]); ok && n > 0 {
+		if q := v / n; math.Abs(q-math.Round(q)) > 1e-9 {
+			fail("value must be a multiple of %v", n)
+		}
+	}
+}
+
+func (s *Schema) validateObject(obj map[string]any, v map[string]any, path string, out *[]Violation, fail func(string, ...any)) error {
+	if required, ok := obj["required"].([]any); ok {
+		for _, r := range required {
+			if name, ok := r.(string); ok {
+				if _, found := v[name]; !found {
+					fail("missing required property %q", name)
+				}
+			}
+		}
+	}
+
+	if n, ok := number(obj["minProperties"]); ok && float64(len(v)) < n {
+		fail("object must have at least %v properties", n)
+	}
+
+	if n, ok := number(obj["maxProperties"]); ok && float64(len(v)) > n {
+		fail("object must have at most %v properties", n)
+	}
+
+	if deps, ok := obj["dependentRequired"].(map[string]any); ok {
+		for _, name := range sortedKeys(deps) {
+			if _, found := v[name]; !found {
+				continue
+			}
+
+			list, _ := deps[name].([]any)
+			for _, d := range list {
+				if dep, ok := d.(string); ok {
+					if _, found := v[dep]; !found {
+						fail("property %q is required when %q is set", dep, name)
+					}
+				}
+			}
+		}
+	}
+
+	props, _ := obj["properties"].(map[string]any)
+	patterns, _ := obj["patternProperties"].(map[string]any)
+	additional, hasAdditional := obj["additionalProperties"]
+	propertyNames, hasPropertyNames := obj["propertyNames"]
+
+	for _, name := range sortedKeys(v) {
+		child := path + "/" + escapePointer(name)
+		matched := false
+
+		if hasPropertyNames {
+			valid, err := s.isValid(propertyNames, name, child)
+			if err != nil {
+				return err
+			}
+
+			if !valid {
+				*out = append(*out, Violation{Path: child, Message: fmt.Sprintf("property name %q is not allowed", name)})
+			}
+		}
+
+		if prop, found := props[name]; found {
+			matched = true
+			if err := s.validate(prop, v[name], child, out); err != nil {
+				return err
+			}
+		}
+
+		for _, pattern := range sortedKeys(patterns) {
+			re, err := s.regexp(pattern)
+			if err != nil {
+				return err
+			}
+
+			if re.MatchString(name) {
+				matched = true
+				if err := s.validate(patterns[pattern], v[name], child, out); err != nil {
+					return err
+				}
+			}
+		}
+
+		if matched || !hasAdditional {
+			continue
+		}
+
+		if allowed, ok := additional.(bool); ok && !allowed {
+			*out = append(*out, Violation{Path: child, Message: fmt.Sprintf("property %q is not allowed", name)})
+			continue
+		}
+
+		if err := s.validate(additional, v[name], child, out); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Schema) validateArray(obj map[string]any, v []any, path string, out *[]Violation, fail func(string, ...any)) error {
+	if n, ok := number(obj["minItems"]); ok && float64(len(v)) < n {
+		fail("array must have at least %v items", n)
+	}
+
+	if n, ok := number(obj["maxItems"]); ok && float64(len(v)) > n {
+		fail("array must have at most %v items", n)
+	}
+
+	if unique, ok := obj["uniqueItems"].(bool); ok && unique {
+	outer:
+		for i := range v {
+			for j := i + 1; j < len(v); j++ {
+				if equal(normalize(v[i]), normalize(v[j])) {
+					fail("array items must be unique, items %d and %d are equal", i, j)
+					break outer
+				}
+			}
+		}
+	}
+
+	prefix, _ := obj["prefixItems"].([]any)
+	for i := 0; i < len(prefix) && i < len(v); i++ {
+		if err := s.validate(prefix[i], v[i], path+"/"+strconv.Itoa(i), out); err != nil {
+			return err
+		}
+	}
+
+	if items, found := obj["items"]; found {
+		for i := len(prefix); i < len(v); i++ {
+			if err := s.validate(items, v[i], path+"/"+strconv.Itoa(i), out); err != nil {
+				return err
+			}
+		}
+	}
+
+	if contains, found := obj["contains"]; found {
+		count := 0
+		for i, item := range v {
+			valid, err := s.isValid(contains, item, path+"/"+strconv.Itoa(i))
+			if err != nil {
+				return err
+			}
+
+			if valid {
+				count++
+			}
+		}
+
+		minContains := 1.0
+		if n, ok := number(obj["minContains"]); ok {
+			minContains = n
+		}
+
+		if float64(count) < minContains {
+			fail("array must contain at least %v matching items, found %d", minContains, count)
+		}
+
+		if n, ok := number(obj["maxContains"]); ok && float64(count) > n {
+			fail("array must contain at most %v matching items, found %d", n, count)
+		}
+	}
+
+	return nil
+}
+
+func (s *Schema) validateCombinators(obj map[string]any, v any, path string, out *[]Violation, fail func(string, ...any)) error {
+	if all, ok := obj["allOf"].([]any); ok {
+		for _, sub := range all {
+			if err := s.validate(sub, v, path, out); err != nil {
+				return err
+			}
+		}
+	}
+
+	if anyOf, ok := obj["anyOf"].([]any); ok {
+		matched := false
+		for _, sub := range anyOf {
+			valid, err := s.isValid(sub, v, path)
+			if err != nil {
+				return err
+			}
+
+			if valid {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			fail("value must match at least one schema in anyOf")
+		}
+	}
+
+	if oneOf, ok := obj["oneOf"].([]any); ok {
+		count := 0
+		for _, sub := range oneOf {
+			valid, err := s.isValid(sub, v, path)
+			if err != nil {
+				return err
+			}
+
+			if valid {
+				count++
+			}
+		}
+
+		if count != 1 {
+			fail("value must match exactly one schema in oneOf, matched %d", count)
+		}
+	}
+
+	if not, found := obj["not"]; found {
+		valid, err := s.isValid(not, v, path)
+		if err != nil {
+			return err
+		}
+
+		if valid {
+			fail("value must not match the schema in not")
+		}
+	}
+
+	if cond, found := obj["if"]; found {
+		valid, err := s.isValid(cond, v, path)
+		if err != nil {
+			return err
+		}
+
+		branch, hasBranch := obj["else"]
+		if valid {
+			branch, hasBranch = obj["then"]
+		}
+
+		if hasBranch {
+			if err := s.validate(branch, v, path, out); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// normalize converts every numeric type into float64, which is how numbers
+// are represented when decoding JSON, so values coming from YAML files or
+// command line flags can be compared against the schema. Times, like TOML
+// dates, become RFC 3339 strings, the way they're written in JSON.
+func normalize(v any) any {
+	switch t := v.(type) {
+	case map[string]any, []any, string, bool, nil, float64:
+		return v
+	case time.Time:
+		return t.Format(time.RFC3339Nano)
+	}
+
+	if n, ok := number(v); ok {
+		return n
+	}
+
+	return v
+}
+
+func number(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+
+	return 0, false
+}
+
+func typeOf(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case float64:
+		if val == math.Trunc(val) {
+			return "integer"
+		}
+
+		return "number"
+	}
+
+	return fmt.Sprintf("unsupported type %T", v)
+}
+
+func matchesType(v any, allowed []string) bool {
+	actual := typeOf(v)
+	for _, t := range allowed {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// equal compares two normalized values for equality as defined by JSON Schema
+func equal(a, b any) bool {
+	switch av := a.(type) {
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+
+		for k, v := range av {
+			other, found := bv[k]
+			if !found || !equal(normalize(v), normalize(other)) {
+				return false
+			}
+		}
+
+		return true
+	case []any:
+		bv, ok := b.([]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+
+		for i := range av {
+			if !equal(normalize(av[i]), normalize(bv[i])) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+func deepCopy(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		cp := make(map[string]any, len(val))
+		for k, item := range val {
+			cp[k] = deepCopy(item)
+		}
+
+		return cp
+	case []any:
+		cp := make([]any, len(val))
+		for i, item := range val {
+			cp[i] = deepCopy(item)
+		}
+
+		return cp
+	case float64:
+		// Integral defaults are handed to templates as integers, the
+		// same way YAML values files decode them
+		if val == math.Trunc(val) && math.Abs(val) < math.MaxInt32 {
+			return int(val)
+		}
+	}
+
+	return v
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+func escapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
+
+func formatValue(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+
+	return string(b)
+}
+
+func formatList(list []any) string {
+	items := make([]string, 0, len(list))
+	for _, item := range list {
+		items = append(items, formatValue(item))
+	}
+
+	return strings.Join(items, ", ")
+}
@@ -0,0 +1,212 @@
+package jsonschema
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   string
+		value    any
+		expected []Violation
+	}{
+		{
+			name:   "valid object",
+			schema: `{"type": "object", "properties": {"port": {"type": "integer"}, "name": {"type": "string"}}}`,
+			value:  map[string]any{"port": 8080, "name": "app"},
+		},
+		{
+			name:   "every violation is reported",
+			schema: `{"type": "object", "required": ["name"], "properties": {"port": {"type": "integer", "maximum": 65535}, "debug": {"type": "boolean"}}}`,
+			value:  map[string]any{"port": 70000, "debug": "yes"},
+			expected: []Violation{
+				{Path: "", Message: `missing required property "name"`},
+				{Path: "/debug", Message: "expected boolean, got string"},
+				{Path: "/port", Message: "value must be less than or equal to 65535"},
+			},
+		},
+		{
+			name:   "nested paths and arrays",
+			schema: `{"properties": {"servers": {"type": "array", "items": {"type": "object", "properties": {"port": {"type": "integer"}}}}}}`,
+			value:  map[string]any{"servers": []any{map[string]any{"port": 80}, map[string]any{"port": "http"}}},
+			expected: []Violation{
+				{Path: "/servers/1/port", Message: "expected integer, got string"},
+			},
+		},
+		{
+			name:   "integers accept integral floats",
+			schema: `{"type": "integer"}`,
+			value:  3.0,
+		},
+		{
+			name:     "integers reject fractions",
+			schema:   `{"type": "integer"}`,
+			value:    1.5,
+			expected: []Violation{{Path: "", Message: "expected integer, got number"}},
+		},
+		{
+			name:     "enum",
+			schema:   `{"enum": ["debug", "info"]}`,
+			value:    "trace",
+			expected: []Violation{{Path: "", Message: `value must be one of "debug", "info"`}},
+		},
+		{
+			name:   "strings",
+			schema: `{"type": "string", "minLength": 3, "pattern": "^[a-z]+$"}`,
+			value:  "A",
+			expected: []Violation{
+				{Path: "", Message: "string must be at least 3 characters long"},
+				{Path: "", Message: `string must match pattern "^[a-z]+$"`},
+			},
+		},
+		{
+			name:   "additional properties",
+			schema: `{"properties": {"a": {}}, "patternProperties": {"^x-": {}}, "additionalProperties": false}`,
+			value:  map[string]any{"a": 1, "x-custom": 2, "b": 3},
+			expected: []Violation{
+				{Path: "/b", Message: `property "b" is not allowed`},
+			},
+		},
+		{
+			name:   "references",
+			schema: `{"$defs": {"port": {"type": "integer", "minimum": 1}}, "properties": {"http": {"$ref": "#/$defs/port"}, "https": {"$ref": "#/$defs/port"}}}`,
+			value:  map[string]any{"http": 0, "https": 443},
+			expected: []Violation{
+				{Path: "/http", Message: "value must be greater than or equal to 1"},
+			},
+		},
+		{
+			name:   "combinators",
+			schema: `{"properties": {"a": {"anyOf": [{"type": "string"}, {"type": "integer"}]}, "b": {"oneOf": [{"minimum": 1}, {"minimum": 2}]}, "c": {"not": {"type": "null"}}}}`,
+			value:  map[string]any{"a": true, "b": 5, "c": nil},
+			expected: []Violation{
+				{Path: "/a", Message: "value must match at least one schema in anyOf"},
+				{Path: "/b", Message: "value must match exactly one schema in oneOf, matched 2"},
+				{Path: "/c", Message: "value must not match the schema in not"},
+			},
+		},
+		{
+			name:   "conditionals",
+			schema: `{"if": {"properties": {"tls": {"const": true}}}, "then": {"required": ["cert"]}}`,
+			value:  map[string]any{"tls": true},
+			expected: []Violation{
+				{Path: "", Message: `missing required property "cert"`},
+			},
+		},
+		{
+			name:   "prefix items and unique items",
+			schema: `{"prefixItems": [{"type": "string"}], "items": {"type": "integer"}, "uniqueItems": true}`,
+			value:  []any{"a", 1, 1},
+			expected: []Violation{
+				{Path: "", Message: "array items must be unique, items 1 and 2 are equal"},
+			},
+		},
+		{
+			name:     "false schema",
+			schema:   `{"properties": {"legacy": false}}`,
+			value:    map[string]any{"legacy": "x"},
+			expected: []Violation{{Path: "/legacy", Message: "value is not allowed"}},
+		},
+		{
+			name:     "pointer escaping",
+			schema:   `{"additionalProperties": {"type": "string"}}`,
+			value:    map[string]any{"kubernetes.io/role": 1},
+			expected: []Violation{{Path: "/kubernetes.io~1role", Message: "expected string, got integer"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse([]byte(tt.schema))
+			if err != nil {
+				t.Fatalf("Parse() unexpected error: %v", err)
+			}
+
+			err = s.Validate(tt.value)
+			if tt.expected == nil {
+				if err != nil {
+					t.Errorf("Validate() unexpected error: %v", err)
+				}
+				return
+			}
+
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Validate() error = %v, want *ValidationError", err)
+			}
+
+			if !reflect.DeepEqual(verr.Violations, tt.expected) {
+				t.Errorf("Validate() = %#v, want %#v", verr.Violations, tt.expected)
+			}
+		})
+	}
+}
+
+func TestApplyDefaults(t *testing.T) {
+	s, err := Parse([]byte(`{
+		"$defs": {"db": {"type": "object", "properties": {"port": {"default": 5432}}}},
+		"properties": {
+			"replicas": {"default": 1},
+			"name": {"default": "app"},
+			"db": {"$ref": "#/$defs/db", "default": {}},
+			"cache": {"properties": {"ttl": {"default": "1h"}}}
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+
+	values := map[string]any{"name": "custom"}
+	if err := s.ApplyDefaults(values); err != nil {
+		t.Fatalf("ApplyDefaults() unexpected error: %v", err)
+	}
+
+	expected := map[string]any{
+		"replicas": 1,
+		"name":     "custom",
+		"db":       map[string]any{"port": 5432},
+	}
+
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("ApplyDefaults() = %v, want %v", values, expected)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, schema := range []string{`[]`, `{`, `"string"`} {
+		if _, err := Parse([]byte(schema)); err == nil {
+			t.Errorf("Parse(%s) expected error but got none", schema)
+		}
+	}
+}
+
+func TestValidateReferences(t *testing.T) {
+	// Recursive schemas validate values of any depth
+	s, err := Parse([]byte(`{"$defs": {"node": {"type": "object", "properties": {"child": {"$ref": "#/$defs/node"}, "n": {"type": "integer"}}}}, "$ref": "#/$defs/node"}`))
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+
+	value := map[string]any{"n": 0}
+	for i := range maxRefDepth * 2 {
+		value = map[string]any{"n": i, "child": value}
+	}
+
+	if err := s.Validate(value); err != nil {
+		t.Errorf("Validate() unexpected error: %v", err)
+	}
+
+	// References that loop without descending into the value fail
+	s, err = Parse([]byte(`{"$defs": {"x": {"$ref": "#/$defs/y"}, "y": {"$ref": "#/$defs/x"}}, "properties": {"a": {"$ref": "#/$defs/x"}}}`))
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+
+	var verr *ValidationError
+	if err := s.Validate(map[string]any{"a": 1}); err == nil || errors.As(err, &verr) {
+		t.Errorf("Validate() error = %v, want a schema error", err)
+	}
+}
@@ -0,0 +1,76 @@
+// Package maputil merges and copies the nested maps values are decoded into
+package maputil
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// Copy returns a deep copy of m, copying every nested map
+func Copy(m map[string]any) map[string]any {
+	cp := make(map[string]any)
+	for k, v := range m {
+		vm, ok := v.(map[string]any)
+		if ok {
+			cp[k] = Copy(vm)
+		} else {
+			cp[k] = v
+		}
+	}
+
+	return cp
+}
+
+// Merge deeply merges two maps, with values from the second map taking precedence
+func Merge(dest, src map[string]any) map[string]any {
+	if dest == nil {
+		dest = make(map[string]any)
+	}
+
+	result := Copy(dest)
+
+	for k, v := range src {
+		if srcMap, ok := v.(map[string]any); ok {
+			if destMap, exists := result[k]; exists {
+				if destMapTyped, ok := destMap.(map[string]any); ok {
+					result[k] = Merge(destMapTyped, srcMap)
+				} else {
+					// If destination is not a map, replace it
+					result[k] = Copy(srcMap)
+				}
+			} else {
+				result[k] = Copy(srcMap)
+			}
+		} else {
+			// For non-map values, the source value takes precedence
+			result[k] = v
+		}
+	}
+
+	return result
+}
+
+// NormalizeJSON converts the JSON numbers in v, decoded with UseNumber, into
+// int when they're integers and float64 otherwise, matching the types used
+// for YAML values. Maps and slices are converted in place.
+func NormalizeJSON(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, item := range val {
+			val[k] = NormalizeJSON(item)
+		}
+	case []any:
+		for i, item := range val {
+			val[i] = NormalizeJSON(item)
+		}
+	case json.Number:
+		if n, err := strconv.ParseInt(val.String(), 10, 0); err == nil {
+			return int(n)
+		}
+
+		f, _ := val.Float64()
+		return f
+	}
+
+	return v
+}
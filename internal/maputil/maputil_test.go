@@ -0,0 +1,188 @@
+package maputil
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name     string
+		dest     map[string]any
+		src      map[string]any
+		expected map[string]any
+	}{
+		{
+			name: "merge simple maps",
+			dest: map[string]any{
+				"key1": "value1",
+			},
+			src: map[string]any{
+				"key2": "value2",
+			},
+			expected: map[string]any{
+				"key1": "value1",
+				"key2": "value2",
+			},
+		},
+		{
+			name: "override existing key",
+			dest: map[string]any{
+				"key": "oldvalue",
+			},
+			src: map[string]any{
+				"key": "newvalue",
+			},
+			expected: map[string]any{
+				"key": "newvalue",
+			},
+		},
+		{
+			name: "merge nested maps",
+			dest: map[string]any{
+				"config": map[string]any{
+					"existing": "value",
+				},
+			},
+			src: map[string]any{
+				"config": map[string]any{
+					"new": "value",
+				},
+			},
+			expected: map[string]any{
+				"config": map[string]any{
+					"existing": "value",
+					"new":      "value",
+				},
+			},
+		},
+		{
+			name: "override nested value",
+			dest: map[string]any{
+				"config": map[string]any{
+					"key": "oldvalue",
+				},
+			},
+			src: map[string]any{
+				"config": map[string]any{
+					"key": "newvalue",
+				},
+			},
+			expected: map[string]any{
+				"config": map[string]any{
+					"key": "newvalue",
+				},
+			},
+		},
+		{
+			name: "replace non-map with map",
+			dest: map[string]any{
+				"key": "stringvalue",
+			},
+			src: map[string]any{
+				"key": map[string]any{
+					"nested": "value",
+				},
+			},
+			expected: map[string]any{
+				"key": map[string]any{
+					"nested": "value",
+				},
+			},
+		},
+		{
+			name: "nil dest map",
+			dest: nil,
+			src: map[string]any{
+				"key": "value",
+			},
+			expected: map[string]any{
+				"key": "value",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Merge(tt.dest, tt.src)
+
+			if !mapsEqual(result, tt.expected) {
+				t.Errorf("Merge() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+// mapsEqual compares two maps for equality, handling nested maps
+func mapsEqual(a, b map[string]any) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for k, v := range a {
+		bv, exists := b[k]
+		if !exists {
+			return false
+		}
+
+		if !valuesEqual(v, bv) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// valuesEqual compares two values for equality, handling nested maps and slices
+func valuesEqual(a, b any) bool {
+	switch av := a.(type) {
+	case map[string]any:
+		if bv, ok := b.(map[string]any); ok {
+			return mapsEqual(av, bv)
+		}
+		return false
+	case []any:
+		if bv, ok := b.([]any); ok {
+			return slicesEqual(av, bv)
+		}
+		return false
+	case nil:
+		return b == nil
+	default:
+		return a == b
+	}
+}
+
+// slicesEqual compares two slices for equality
+func slicesEqual(a, b []any) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i, av := range a {
+		if !valuesEqual(av, b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestNormalizeJSON(t *testing.T) {
+	input := map[string]any{
+		"port":  json.Number("8080"),
+		"ratio": json.Number("0.5"),
+		"list":  []any{json.Number("1"), "two", map[string]any{"big": json.Number("1e3")}},
+	}
+
+	expected := map[string]any{
+		"port":  8080,
+		"ratio": 0.5,
+		"list":  []any{1, "two", map[string]any{"big": float64(1000)}},
+	}
+
+	if got := NormalizeJSON(input); !reflect.DeepEqual(got, expected) {
+		t.Errorf("NormalizeJSON() = %#v, want %#v", got, expected)
+	}
+}
@@ -1,9 +1,12 @@
 package setflags
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
+
+	"github.com/patrickdappollonio/tgen/internal/maputil"
 )
 
 // KeyValuePair represents a parsed key-value pair
@@ -21,70 +24,142 @@ type KeyPart struct {
 
 // ParseSetValues parses Helm-style --set values into a nested map structure with type inference
 func ParseSetValues(setValues []string) (map[string]any, error) {
+	return Parse(flagsOf(KindSet, setValues), nil)
+}
+
+// ParseSetStringValues parses Helm-style --set-string values into a nested map structure (all values as strings)
+func ParseSetStringValues(setValues []string) (map[string]any, error) {
+	return Parse(flagsOf(KindString, setValues), nil)
+}
+
+// Kinds of set flags, named after the command line flag for each of them
+const (
+	KindSet    = "set"
+	KindString = "set-string"
+	KindFile   = "set-file"
+	KindJSON   = "set-json"
+)
+
+// Flag is a single value given to one of the set flags
+type Flag struct {
+	Kind  string
+	Value string
+}
+
+// Parse parses the values of every kind of set flag into a single nested map
+// structure. Flags are applied in the order given, so later flags take
+// precedence over earlier ones regardless of their kind. The readFile
+// function loads the contents of the files given to --set-file.
+func Parse(flags []Flag, readFile func(path string) (string, error)) (map[string]any, error) {
 	result := make(map[string]any)
 
-	for _, setValue := range setValues {
-		if setValue == "" {
-			continue
+	for _, flag := range flags {
+		var err error
+
+		switch flag.Kind {
+		case KindSet:
+			err = parseInto(result, flag.Value, func(value string) (any, error) {
+				return parseValueWithAdvancedSyntax(value, true)
+			})
+		case KindString:
+			err = parseInto(result, flag.Value, func(value string) (any, error) {
+				return parseValueWithAdvancedSyntax(value, false)
+			})
+		case KindFile:
+			err = parseInto(result, flag.Value, func(value string) (any, error) {
+				return readFile(unescapeValue(value))
+			})
+		case KindJSON:
+			err = parseJSONInto(result, flag.Value)
+		default:
+			err = fmt.Errorf("unknown set flag kind %q", flag.Kind)
 		}
 
-		// Parse comma-separated values within a single --set flag
-		pairs, err := parseCommaSeparatedPairs(setValue)
 		if err != nil {
 			return nil, err
 		}
+	}
 
-		for _, pair := range pairs {
-			key, value := pair.Key, pair.Value
+	return result, nil
+}
 
-			// Parse the value with type inference (including special values like null, arrays)
-			parsedValue, err := parseValueWithAdvancedSyntax(value, true)
-			if err != nil {
-				return nil, fmt.Errorf("invalid value for key %s: %w", key, err)
-			}
+func flagsOf(kind string, values []string) []Flag {
+	flags := make([]Flag, 0, len(values))
+	for _, v := range values {
+		flags = append(flags, Flag{Kind: kind, Value: v})
+	}
 
-			// Set the value in the nested structure
-			if err := setNestedValue(result, key, parsedValue); err != nil {
-				return nil, err
-			}
+	return flags
+}
+
+// parseInto parses the comma-separated pairs of a single flag value into
+// result, converting each value with parseValue
+func parseInto(result map[string]any, setValue string, parseValue func(value string) (any, error)) error {
+	if setValue == "" {
+		return nil
+	}
+
+	pairs, err := parseCommaSeparatedPairs(setValue)
+	if err != nil {
+		return err
+	}
+
+	for _, pair := range pairs {
+		parsedValue, err := parseValue(pair.Value)
+		if err != nil {
+			return fmt.Errorf("invalid value for key %s: %w", pair.Key, err)
+		}
+
+		if err := setNestedValue(result, pair.Key, parsedValue); err != nil {
+			return err
 		}
 	}
 
-	return result, nil
+	return nil
 }
 
-// ParseSetStringValues parses Helm-style --set-string values into a nested map structure (all values as strings)
-func ParseSetStringValues(setValues []string) (map[string]any, error) {
-	result := make(map[string]any)
+// parseJSONInto parses "key=<json>" pairs, separated by commas, into result.
+// Since JSON values can contain commas, each value is read until the JSON
+// literal ends rather than split beforehand.
+func parseJSONInto(result map[string]any, setValue string) error {
+	rest := strings.TrimSpace(setValue)
 
-	for _, setValue := range setValues {
-		if setValue == "" {
-			continue
+	for rest != "" {
+		key, value, found := strings.Cut(rest, "=")
+		if !found {
+			return fmt.Errorf("invalid pair format: %s (expected key=<json>)", rest)
 		}
 
-		// Parse comma-separated values within a single --set-string flag
-		pairs, err := parseCommaSeparatedPairs(setValue)
-		if err != nil {
-			return nil, err
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return fmt.Errorf("empty key in pair: %s", rest)
 		}
 
-		for _, pair := range pairs {
-			key, value := pair.Key, pair.Value
+		dec := json.NewDecoder(strings.NewReader(value))
+		dec.UseNumber()
 
-			// Parse the value but force everything to strings (no type inference)
-			parsedValue, err := parseValueWithAdvancedSyntax(value, false)
-			if err != nil {
-				return nil, fmt.Errorf("invalid value for key %s: %w", key, err)
-			}
+		var parsed any
+		if err := dec.Decode(&parsed); err != nil {
+			return fmt.Errorf("invalid JSON value for key %s: %w", key, err)
+		}
 
-			// Set the value in the nested structure
-			if err := setNestedValue(result, key, parsedValue); err != nil {
-				return nil, err
-			}
+		if err := setNestedValue(result, key, maputil.NormalizeJSON(parsed)); err != nil {
+			return err
+		}
+
+		rest = strings.TrimSpace(value[dec.InputOffset():])
+		if rest == "" {
+			break
+		}
+
+		if rest[0] != ',' {
+			return fmt.Errorf("invalid JSON value for key %s: unexpected %q after value", key, rest)
 		}
+
+		rest = strings.TrimSpace(rest[1:])
 	}
 
-	return result, nil
+	return nil
 }
 
 // parseCommaSeparatedPairs parses comma-separated key=value pairs with proper escaping
@@ -1,6 +1,7 @@
 package setflags
 
 import (
+	"fmt"
 	"testing"
 )
 
@@ -282,6 +283,123 @@ func TestParseValueWithTypeInference(t *testing.T) {
 	}
 }
 
+func TestParseSetFile(t *testing.T) {
+	files := map[string]string{
+		"cert.pem":       "-----BEGIN CERTIFICATE-----\nabc\n",
+		"dir/config.ini": "[main]\nport=80",
+	}
+
+	readFile := func(path string) (string, error) {
+		contents, found := files[path]
+		if !found {
+			return "", fmt.Errorf("open %s: no such file or directory", path)
+		}
+
+		return contents, nil
+	}
+
+	result, err := Parse(flagsOf(KindFile, []string{`tls.cert=cert.pem,configs[1]=dir/config.ini`}), readFile)
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+
+	expected := map[string]any{
+		"tls":     map[string]any{"cert": "-----BEGIN CERTIFICATE-----\nabc\n"},
+		"configs": []any{nil, "[main]\nport=80"},
+	}
+
+	if !mapsEqual(result, expected) {
+		t.Errorf("Parse() = %v, want %v", result, expected)
+	}
+
+	if _, err := Parse(flagsOf(KindFile, []string{"key=missing.txt"}), readFile); err == nil {
+		t.Error("Parse() expected error for a missing file but got none")
+	}
+}
+
+func TestParseSetJSON(t *testing.T) {
+	tests := []struct {
+		name      string
+		setValues []string
+		expected  map[string]any
+		wantErr   bool
+	}{
+		{
+			name:      "object",
+			setValues: []string{`resources={"limits": {"cpu": "500m", "memory": 128}}`},
+			expected: map[string]any{
+				"resources": map[string]any{"limits": map[string]any{"cpu": "500m", "memory": 128}},
+			},
+		},
+		{
+			name:      "several pairs with commas inside values",
+			setValues: []string{`ports=[80, 443],ratio=0.5 , enabled=true`},
+			expected: map[string]any{
+				"ports":   []any{80, 443},
+				"ratio":   0.5,
+				"enabled": true,
+			},
+		},
+		{
+			name:      "array index and escaped dots",
+			setValues: []string{`servers[0].tags=["a"]`, `annotations.kubernetes\.io/role=null`},
+			expected: map[string]any{
+				"servers":     []any{map[string]any{"tags": []any{"a"}}},
+				"annotations": map[string]any{"kubernetes.io/role": nil},
+			},
+		},
+		{
+			name:      "invalid json",
+			setValues: []string{`key={"a":}`},
+			wantErr:   true,
+		},
+		{
+			name:      "trailing garbage",
+			setValues: []string{`key=1 2`},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Parse(flagsOf(KindJSON, tt.setValues), nil)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Parse() expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Parse() unexpected error: %v", err)
+			}
+
+			if !mapsEqual(result, tt.expected) {
+				t.Errorf("Parse() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseOrder(t *testing.T) {
+	flags := []Flag{
+		{Kind: KindSet, Value: "port=80,name=web"},
+		{Kind: KindJSON, Value: `port="http"`},
+		{Kind: KindString, Value: "replicas=3"},
+		{Kind: KindSet, Value: "replicas=5"},
+	}
+
+	result, err := Parse(flags, nil)
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+
+	expected := map[string]any{"port": "http", "name": "web", "replicas": 5}
+	if !mapsEqual(result, expected) {
+		t.Errorf("Parse() = %v, want %v", result, expected)
+	}
+}
+
 // Helper functions for testing
 func mapsEqual(a, b map[string]any) bool {
 	if len(a) != len(b) {
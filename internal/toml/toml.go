@@ -0,0 +1,829 @@
+// Package toml implements a parser for TOML v1.0 documents. Documents are
+// decoded into the same generic shape the YAML and JSON decoders produce:
+// tables become map[string]any, arrays become []any, integers become int,
+// floats become float64, and dates and date-times become time.Time. Local
+// times, which have no date, are kept as strings.
+package toml
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Error is a syntax error found while parsing a document
+type Error struct {
+	Line    int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// Parse decodes a TOML document
+func Parse(data []byte) (map[string]any, error) {
+	if !utf8.Valid(data) {
+		return nil, &Error{Line: 1, Message: "document is not valid UTF-8"}
+	}
+
+	p := &parser{
+		src:     string(data),
+		root:    map[string]any{},
+		defined: map[string]bool{},
+		arrays:  map[string]bool{},
+		static:  map[string]bool{},
+		dotted:  map[string]bool{},
+	}
+
+	p.current = p.root
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+
+	return p.root, nil
+}
+
+type parser struct {
+	src string
+	pos int
+
+	root map[string]any
+
+	// current is the table key/value pairs are added to, and currentPath
+	// its unique path, used to track which tables were defined
+	current     map[string]any
+	currentPath string
+
+	// defined holds the paths of tables defined by a [table] header, or
+	// through dotted keys, which can't be defined again
+	defined map[string]bool
+
+	// arrays holds the paths of arrays of tables, defined by [[array]]
+	// headers, which can be appended to
+	arrays map[string]bool
+
+	// static holds the paths of inline tables and arrays, which can't be
+	// extended once defined
+	static map[string]bool
+
+	// dotted holds the paths of tables defined through dotted keys, which
+	// can be extended by other dotted keys within the same table
+	dotted map[string]bool
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return &Error{
+		Line:    strings.Count(p.src[:min(p.pos, len(p.src))], "\n") + 1,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *parser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+
+	return p.src[p.pos]
+}
+
+func (p *parser) hasPrefix(s string) bool {
+	return strings.HasPrefix(p.src[p.pos:], s)
+}
+
+// skipSpace skips spaces and tabs
+func (p *parser) skipSpace() {
+	for !p.eof() && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// skipComment skips a comment up to, but not including, the end of the line
+func (p *parser) skipComment() error {
+	if p.peek() != '#' {
+		return nil
+	}
+
+	for !p.eof() && p.src[p.pos] != '\n' {
+		if c := p.src[p.pos]; isControl(c) && c != '\t' && !(c == '\r' && p.hasPrefix("\r\n")) {
+			return p.errorf("control character %q is not allowed in comments", c)
+		}
+
+		p.pos++
+	}
+
+	return nil
+}
+
+// skipBlank skips whitespace, newlines and comments, as allowed in arrays
+func (p *parser) skipBlank() error {
+	for {
+		p.skipSpace()
+		if err := p.skipComment(); err != nil {
+			return err
+		}
+
+		switch {
+		case p.hasPrefix("\n"):
+			p.pos++
+		case p.hasPrefix("\r\n"):
+			p.pos += 2
+		default:
+			return nil
+		}
+	}
+}
+
+// endOfLine consumes the rest of the line after an expression, which can
+// only hold whitespace and a comment
+func (p *parser) endOfLine() error {
+	p.skipSpace()
+	if err := p.skipComment(); err != nil {
+		return err
+	}
+
+	switch {
+	case p.eof():
+		return nil
+	case p.hasPrefix("\n"):
+		p.pos++
+		return nil
+	case p.hasPrefix("\r\n"):
+		p.pos += 2
+		return nil
+	default:
+		return p.errorf("expected a new line after expression, found %q", p.peek())
+	}
+}
+
+func (p *parser) parse() error {
+	for {
+		if err := p.skipBlank(); err != nil {
+			return err
+		}
+
+		if p.eof() {
+			return nil
+		}
+
+		var err error
+		switch {
+		case p.hasPrefix("[["):
+			err = p.parseArrayTableHeader()
+		case p.hasPrefix("["):
+			err = p.parseTableHeader()
+		default:
+			err = p.parseKeyValue(p.current, p.currentPath)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err := p.endOfLine(); err != nil {
+			return err
+		}
+	}
+}
+
+// joinPath appends key to a table path
+func joinPath(path, key string) string {
+	return path + "." + strconv.Quote(key)
+}
+
+// descend returns the table at key within table, creating it if needed.
+// When key holds an array of tables, its last element is returned.
+func (p *parser) descend(table map[string]any, path, key string) (map[string]any, string, error) {
+	child := joinPath(path, key)
+
+	switch v := table[key].(type) {
+	case nil:
+		m := map[string]any{}
+		table[key] = m
+		return m, child, nil
+	case map[string]any:
+		if p.static[child] {
+			return nil, "", p.errorf("unable to extend inline table %q", key)
+		}
+
+		return v, child, nil
+	case []any:
+		if !p.arrays[child] {
+			return nil, "", p.errorf("unable to extend static array %q", key)
+		}
+
+		last := v[len(v)-1].(map[string]any)
+		return last, fmt.Sprintf("%s#%d", child, len(v)-1), nil
+	default:
+		return nil, "", p.errorf("key %q is already defined as %s", key, typeName(v))
+	}
+}
+
+func (p *parser) parseTableHeader() error {
+	p.pos++
+	p.skipSpace()
+
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+
+	p.skipSpace()
+	if !p.hasPrefix("]") {
+		return p.errorf("expected \"]\" to close table header")
+	}
+	p.pos++
+
+	table, path := p.root, ""
+	for _, key := range keys[:len(keys)-1] {
+		if table, path, err = p.descend(table, path, key); err != nil {
+			return err
+		}
+	}
+
+	last := keys[len(keys)-1]
+	path = joinPath(path, last)
+
+	switch v := table[last].(type) {
+	case nil:
+		table[last] = map[string]any{}
+	case map[string]any:
+		// Tables created implicitly by a previous header can be defined
+		// once, but not tables defined by a header, a dotted key or
+		// inline
+		if p.defined[path] || p.static[path] {
+			return p.errorf("table %q is already defined", strings.Join(keys, "."))
+		}
+	default:
+		return p.errorf("key %q is already defined as %s", strings.Join(keys, "."), typeName(v))
+	}
+
+	p.defined[path] = true
+	p.current, p.currentPath = table[last].(map[string]any), path
+	return nil
+}
+
+func (p *parser) parseArrayTableHeader() error {
+	p.pos += 2
+	p.skipSpace()
+
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+
+	p.skipSpace()
+	if !p.hasPrefix("]]") {
+		return p.errorf("expected \"]]\" to close array of tables header")
+	}
+	p.pos += 2
+
+	table, path := p.root, ""
+	for _, key := range keys[:len(keys)-1] {
+		if table, path, err = p.descend(table, path, key); err != nil {
+			return err
+		}
+	}
+
+	last := keys[len(keys)-1]
+	path = joinPath(path, last)
+	element := map[string]any{}
+
+	switch v := table[last].(type) {
+	case nil:
+		table[last] = []any{element}
+		p.arrays[path] = true
+	case []any:
+		if !p.arrays[path] {
+			return p.errorf("unable to extend static array %q", last)
+		}
+
+		table[last] = append(v, element)
+	default:
+		return p.errorf("key %q is already defined as %s", last, typeName(v))
+	}
+
+	p.current = element
+	p.currentPath = fmt.Sprintf("%s#%d", path, len(table[last].([]any))-1)
+	return nil
+}
+
+// parseKeyValue parses a "key = value" pair into table
+func (p *parser) parseKeyValue(table map[string]any, path string) error {
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+
+	p.skipSpace()
+	if !p.hasPrefix("=") {
+		return p.errorf("expected \"=\" after key %q", strings.Join(keys, "."))
+	}
+	p.pos++
+	p.skipSpace()
+
+	// Dotted keys define the intermediate tables
+	for _, key := range keys[:len(keys)-1] {
+		child := joinPath(path, key)
+		if existing, found := table[key]; found {
+			if _, ok := existing.(map[string]any); !ok || p.static[child] || (p.defined[child] && !p.dotted[child]) {
+				return p.errorf("key %q is already defined", key)
+			}
+		}
+
+		if table, path, err = p.descend(table, path, key); err != nil {
+			return err
+		}
+
+		p.defined[path] = true
+		p.dotted[path] = true
+	}
+
+	last := keys[len(keys)-1]
+	if _, found := table[last]; found {
+		return p.errorf("key %q is already defined", strings.Join(keys, "."))
+	}
+
+	value, err := p.parseValue(joinPath(path, last))
+	if err != nil {
+		return err
+	}
+
+	table[last] = value
+	return nil
+}
+
+var reBareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+`)
+
+// parseKey parses a possibly dotted key into its parts
+func (p *parser) parseKey() ([]string, error) {
+	var keys []string
+
+	for {
+		var key string
+		switch p.peek() {
+		case '"':
+			s, err := p.parseBasicString()
+			if err != nil {
+				return nil, err
+			}
+			key = s
+		case '\'':
+			s, err := p.parseLiteralString()
+			if err != nil {
+				return nil, err
+			}
+			key = s
+		default:
+			key = reBareKey.FindString(p.src[p.pos:])
+			if key == "" {
+				if p.eof() {
+					return nil, p.errorf("expected a key, found end of document")
+				}
+
+				return nil, p.errorf("expected a key, found %q", p.peek())
+			}
+			p.pos += len(key)
+		}
+
+		keys = append(keys, key)
+
+		p.skipSpace()
+		if p.peek() != '.' {
+			return keys, nil
+		}
+
+		p.pos++
+		p.skipSpace()
+	}
+}
+
+// parseValue parses any value. The path is used to mark inline tables and
+// arrays as static.
+func (p *parser) parseValue(path string) (any, error) {
+	switch {
+	case p.eof():
+		return nil, p.errorf("expected a value, found end of document")
+	case p.hasPrefix(`"""`):
+		return p.parseMultilineBasicString()
+	case p.hasPrefix(`'''`):
+		return p.parseMultilineLiteralString()
+	case p.peek() == '"':
+		return p.parseBasicString()
+	case p.peek() == '\'':
+		return p.parseLiteralString()
+	case p.peek() == '[':
+		return p.parseArray(path)
+	case p.peek() == '{':
+		return p.parseInlineTable(path)
+	case p.hasPrefix("true") && !isBareKeyChar(p.at(4)):
+		p.pos += 4
+		return true, nil
+	case p.hasPrefix("false") && !isBareKeyChar(p.at(5)):
+		p.pos += 5
+		return false, nil
+	default:
+		return p.parseScalar()
+	}
+}
+
+func (p *parser) at(i int) byte {
+	if p.pos+i >= len(p.src) {
+		return 0
+	}
+
+	return p.src[p.pos+i]
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func isControl(c byte) bool {
+	return c < 0x20 || c == 0x7f
+}
+
+func (p *parser) parseArray(path string) (any, error) {
+	p.pos++
+	p.static[path] = true
+
+	arr := []any{}
+	for {
+		if err := p.skipBlank(); err != nil {
+			return nil, err
+		}
+
+		if p.peek() == ']' {
+			p.pos++
+			return arr, nil
+		}
+
+		value, err := p.parseValue(fmt.Sprintf("%s#%d", path, len(arr)))
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, value)
+
+		if err := p.skipBlank(); err != nil {
+			return nil, err
+		}
+
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+			p.pos++
+			return arr, nil
+		default:
+			return nil, p.errorf("expected \",\" or \"]\" in array")
+		}
+	}
+}
+
+func (p *parser) parseInlineTable(path string) (any, error) {
+	p.pos++
+	p.static[path] = true
+
+	table := map[string]any{}
+	p.skipSpace()
+	if p.peek() == '}' {
+		p.pos++
+		return table, nil
+	}
+
+	for {
+		p.skipSpace()
+		if err := p.parseKeyValue(table, path); err != nil {
+			return nil, err
+		}
+
+		p.skipSpace()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return table, nil
+		default:
+			return nil, p.errorf("expected \",\" or \"}\" in inline table")
+		}
+	}
+}
+
+func (p *parser) parseBasicString() (string, error) {
+	p.pos++
+
+	var sb strings.Builder
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return "", p.errorf("unterminated string")
+		}
+
+		c := p.src[p.pos]
+		switch {
+		case c == '"':
+			p.pos++
+			return sb.String(), nil
+		case c == '\\':
+			if err := p.parseEscape(&sb); err != nil {
+				return "", err
+			}
+		case isControl(c) && c != '\t':
+			return "", p.errorf("control character %q is not allowed in strings", c)
+		default:
+			sb.WriteByte(c)
+			p.pos++
+		}
+	}
+}
+
+func (p *parser) parseMultilineBasicString() (string, error) {
+	p.pos += 3
+	p.skipNewline()
+
+	var sb strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated multi-line string")
+		}
+
+		c := p.src[p.pos]
+		switch {
+		case p.hasPrefix(`"""`):
+			// Up to two quotes can be placed right before the closing ones
+			quotes := 3
+			for quotes < 5 && p.at(quotes) == '"' {
+				quotes++
+			}
+
+			sb.WriteString(strings.Repeat(`"`, quotes-3))
+			p.pos += quotes
+			return sb.String(), nil
+		case c == '\\':
+			// A backslash at the end of a line trims all whitespace
+			// and new lines that follow it
+			rest := strings.TrimLeft(p.src[p.pos+1:], " \t")
+			if strings.HasPrefix(rest, "\n") || strings.HasPrefix(rest, "\r\n") {
+				p.pos = len(p.src) - len(strings.TrimLeft(rest, " \t\r\n"))
+				continue
+			}
+
+			if err := p.parseEscape(&sb); err != nil {
+				return "", err
+			}
+		case c == '\r' && p.hasPrefix("\r\n"):
+			sb.WriteString("\r\n")
+			p.pos += 2
+		case isControl(c) && c != '\t' && c != '\n':
+			return "", p.errorf("control character %q is not allowed in strings", c)
+		default:
+			sb.WriteByte(c)
+			p.pos++
+		}
+	}
+}
+
+func (p *parser) parseLiteralString() (string, error) {
+	p.pos++
+
+	start := p.pos
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return "", p.errorf("unterminated string")
+		}
+
+		c := p.src[p.pos]
+		if c == '\'' {
+			s := p.src[start:p.pos]
+			p.pos++
+			return s, nil
+		}
+
+		if isControl(c) && c != '\t' {
+			return "", p.errorf("control character %q is not allowed in strings", c)
+		}
+
+		p.pos++
+	}
+}
+
+func (p *parser) parseMultilineLiteralString() (string, error) {
+	p.pos += 3
+	p.skipNewline()
+
+	start := p.pos
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated multi-line string")
+		}
+
+		if p.hasPrefix(`'''`) {
+			quotes := 3
+			for quotes < 5 && p.at(quotes) == '\'' {
+				quotes++
+			}
+
+			s := p.src[start : p.pos+quotes-3]
+			p.pos += quotes
+			return s, nil
+		}
+
+		if c := p.src[p.pos]; isControl(c) && c != '\t' && c != '\n' && !(c == '\r' && p.hasPrefix("\r\n")) {
+			return "", p.errorf("control character %q is not allowed in strings", c)
+		}
+
+		p.pos++
+	}
+}
+
+// skipNewline skips a new line right after the opening quotes of a
+// multi-line string, which isn't part of its value
+func (p *parser) skipNewline() {
+	switch {
+	case p.hasPrefix("\n"):
+		p.pos++
+	case p.hasPrefix("\r\n"):
+		p.pos += 2
+	}
+}
+
+func (p *parser) parseEscape(sb *strings.Builder) error {
+	p.pos++
+
+	c := p.peek()
+	p.pos++
+
+	switch c {
+	case 'b':
+		sb.WriteByte('\b')
+	case 't':
+		sb.WriteByte('\t')
+	case 'n':
+		sb.WriteByte('\n')
+	case 'f':
+		sb.WriteByte('\f')
+	case 'r':
+		sb.WriteByte('\r')
+	case '"':
+		sb.WriteByte('"')
+	case '\\':
+		sb.WriteByte('\\')
+	case 'u', 'U':
+		size := 4
+		if c == 'U' {
+			size = 8
+		}
+
+		if p.pos+size > len(p.src) {
+			return p.errorf("invalid unicode escape")
+		}
+
+		n, err := strconv.ParseUint(p.src[p.pos:p.pos+size], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(n)) {
+			return p.errorf("invalid unicode escape %q", p.src[p.pos-2:p.pos+size])
+		}
+
+		sb.WriteRune(rune(n))
+		p.pos += size
+	default:
+		p.pos--
+		return p.errorf("invalid escape sequence \"\\%c\"", c)
+	}
+
+	return nil
+}
+
+var (
+	reDecimal   = regexp.MustCompile(`^[+-]?(0|[1-9](_?[0-9])*)$`)
+	reHex       = regexp.MustCompile(`^0x[0-9A-Fa-f](_?[0-9A-Fa-f])*$`)
+	reOctal     = regexp.MustCompile(`^0o[0-7](_?[0-7])*$`)
+	reBinary    = regexp.MustCompile(`^0b[01](_?[01])*$`)
+	reFloat     = regexp.MustCompile(`^[+-]?(0|[1-9](_?[0-9])*)(\.[0-9](_?[0-9])*)?([eE][+-]?[0-9](_?[0-9])*)?$`)
+	reDate      = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	reLocalTime = regexp.MustCompile(`^\d{2}:\d{2}:\d{2}(\.\d+)?$`)
+	reDateTime  = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}[Tt ]\d{2}:\d{2}:\d{2}(\.\d+)?([Zz]|[+-]\d{2}:\d{2})?$`)
+)
+
+// parseScalar parses numbers, dates and times
+func (p *parser) parseScalar() (any, error) {
+	start := p.pos
+	for !p.eof() && !strings.ContainsRune(" \t\r\n,]}#", rune(p.peek())) {
+		p.pos++
+	}
+
+	// Date-times can use a space to separate the date from the time
+	if reDate.MatchString(p.src[start:p.pos]) && p.peek() == ' ' && isDigit(p.at(1)) && isDigit(p.at(2)) && p.at(3) == ':' {
+		p.pos++
+		for !p.eof() && !strings.ContainsRune(" \t\r\n,]}#", rune(p.peek())) {
+			p.pos++
+		}
+	}
+
+	token := p.src[start:p.pos]
+	if token == "" {
+		return nil, p.errorf("expected a value, found %q", p.peek())
+	}
+
+	switch strings.TrimLeft(token, "+-") {
+	case "inf":
+		if strings.HasPrefix(token, "-") {
+			return math.Inf(-1), nil
+		}
+		return math.Inf(1), nil
+	case "nan":
+		return math.NaN(), nil
+	}
+
+	switch {
+	case reDecimal.MatchString(token):
+		return p.parseInt(token, strings.ReplaceAll(token, "_", ""), 10)
+	case reHex.MatchString(token):
+		return p.parseInt(token, strings.ReplaceAll(token[2:], "_", ""), 16)
+	case reOctal.MatchString(token):
+		return p.parseInt(token, strings.ReplaceAll(token[2:], "_", ""), 8)
+	case reBinary.MatchString(token):
+		return p.parseInt(token, strings.ReplaceAll(token[2:], "_", ""), 2)
+	case reFloat.MatchString(token):
+		f, err := strconv.ParseFloat(strings.ReplaceAll(token, "_", ""), 64)
+		if err != nil {
+			return nil, p.errorf("invalid float %q", token)
+		}
+		return f, nil
+	case reDate.MatchString(token):
+		return p.parseTime(token, "2006-01-02", time.UTC)
+	case reLocalTime.MatchString(token):
+		if _, err := time.Parse("15:04:05.999999999", token); err != nil {
+			return nil, p.errorf("invalid local time %q", token)
+		}
+		return token, nil
+	case reDateTime.MatchString(token):
+		normalized := strings.ToUpper(token[:10] + "T" + token[11:])
+		if strings.HasSuffix(normalized, "Z") || strings.ContainsAny(normalized[19:], "+-") {
+			return p.parseTime(normalized, time.RFC3339Nano, nil)
+		}
+		return p.parseTime(normalized, "2006-01-02T15:04:05.999999999", time.UTC)
+	default:
+		return nil, p.errorf("invalid value %q", token)
+	}
+}
+
+func (p *parser) parseInt(token, digits string, base int) (any, error) {
+	n, err := strconv.ParseInt(digits, base, 64)
+	if err != nil {
+		return nil, p.errorf("invalid integer %q: %s", token, err.(*strconv.NumError).Err)
+	}
+
+	return int(n), nil
+}
+
+func (p *parser) parseTime(token, layout string, loc *time.Location) (any, error) {
+	var t time.Time
+	var err error
+
+	if loc == nil {
+		t, err = time.Parse(layout, token)
+	} else {
+		t, err = time.ParseInLocation(layout, token, loc)
+	}
+
+	if err != nil {
+		return nil, p.errorf("invalid date or time %q", token)
+	}
+
+	return t, nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// typeName describes the kind of a decoded value, with its article, for
+// error messages
+func typeName(v any) string {
+	switch v.(type) {
+	case map[string]any:
+		return "a table"
+	case []any:
+		return "an array"
+	case string:
+		return "a string"
+	case int:
+		return "an integer"
+	case float64:
+		return "a float"
+	case bool:
+		return "a boolean"
+	case time.Time:
+		return "a date-time"
+	default:
+		return fmt.Sprintf("a %T", v)
+	}
+}
@@ -0,0 +1,322 @@
+package toml
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		expected map[string]any
+	}{
+		{
+			name: "scalars",
+			document: `
+title = "TOML \"example\"" # comment
+literal = 'C:\Users\nodejs'
+enabled = true
+disabled = false
+count = 1_000
+negative = -17
+hex = 0xDEAD_beef
+octal = 0o755
+binary = 0b1101
+pi = 3.1415
+exponent = 5e+22
+small = -2E-2
+infinity = -inf
+`,
+			expected: map[string]any{
+				"title":    `TOML "example"`,
+				"literal":  `C:\Users\nodejs`,
+				"enabled":  true,
+				"disabled": false,
+				"count":    1000,
+				"negative": -17,
+				"hex":      0xDEADBEEF,
+				"octal":    0o755,
+				"binary":   13,
+				"pi":       3.1415,
+				"exponent": 5e+22,
+				"small":    -2e-2,
+				"infinity": math.Inf(-1),
+			},
+		},
+		{
+			name: "strings",
+			document: "escapes = \"tab\\there \\u00e9 \\U0001F600\"\n" +
+				"multi = \"\"\"\nline one\nline two\"\"\"\n" +
+				"folded = \"\"\"\\\n    The quick \\\n    brown fox.\"\"\"\n" +
+				"quotes = \"\"\"Here are two quotes: \"\"\"\"\"\n" +
+				"raw = '''\nNo \\escapes\n'''\n",
+			expected: map[string]any{
+				"escapes": "tab\there é 😀",
+				"multi":   "line one\nline two",
+				"folded":  "The quick brown fox.",
+				"quotes":  `Here are two quotes: ""`,
+				"raw":     "No \\escapes\n",
+			},
+		},
+		{
+			name: "tables and keys",
+			document: `
+name = "root"
+site."google.com" = true
+physical.color = "orange"
+physical.shape = "round"
+
+[server]
+host = "localhost"
+
+[server.tls]
+enabled = true
+
+[a.b.c]
+d = 1
+
+[a]
+e = 2
+`,
+			expected: map[string]any{
+				"name":     "root",
+				"site":     map[string]any{"google.com": true},
+				"physical": map[string]any{"color": "orange", "shape": "round"},
+				"server": map[string]any{
+					"host": "localhost",
+					"tls":  map[string]any{"enabled": true},
+				},
+				"a": map[string]any{
+					"b": map[string]any{"c": map[string]any{"d": 1}},
+					"e": 2,
+				},
+			},
+		},
+		{
+			name: "arrays and inline tables",
+			document: `
+ports = [ 8000, 8001, ]
+mixed = [
+  "a", # comment
+  1,
+  [true],
+]
+point = { x = 1, y = { z = 2 } }
+`,
+			expected: map[string]any{
+				"ports": []any{8000, 8001},
+				"mixed": []any{"a", 1, []any{true}},
+				"point": map[string]any{"x": 1, "y": map[string]any{"z": 2}},
+			},
+		},
+		{
+			name: "arrays of tables",
+			document: `
+[[fruits]]
+name = "apple"
+
+[fruits.physical]
+color = "red"
+
+[[fruits.varieties]]
+name = "red delicious"
+
+[[fruits]]
+name = "banana"
+`,
+			expected: map[string]any{
+				"fruits": []any{
+					map[string]any{
+						"name":      "apple",
+						"physical":  map[string]any{"color": "red"},
+						"varieties": []any{map[string]any{"name": "red delicious"}},
+					},
+					map[string]any{"name": "banana"},
+				},
+			},
+		},
+		{
+			name: "nested arrays of tables",
+			document: `
+[[a]]
+[a.b]
+x = 1
+
+[[a.c]]
+y = 1
+
+[[a.c]]
+y = 2
+
+[[a]]
+[a.b]
+x = 2
+`,
+			expected: map[string]any{
+				"a": []any{
+					map[string]any{
+						"b": map[string]any{"x": 1},
+						"c": []any{map[string]any{"y": 1}, map[string]any{"y": 2}},
+					},
+					map[string]any{"b": map[string]any{"x": 2}},
+				},
+			},
+		},
+		{
+			name: "dotted keys",
+			document: `
+a . "b.c" . d = 1
+3.14159 = "pi"
+'quoted'."key" = 2
+point = { x.y = 1, x.z = 2 }
+
+[fruit]
+apple.color = "red"
+apple.taste.sweet = true
+
+[fruit.apple.texture]
+smooth = true
+`,
+			expected: map[string]any{
+				"a":      map[string]any{"b.c": map[string]any{"d": 1}},
+				"3":      map[string]any{"14159": "pi"},
+				"quoted": map[string]any{"key": 2},
+				"point":  map[string]any{"x": map[string]any{"y": 1, "z": 2}},
+				"fruit": map[string]any{
+					"apple": map[string]any{
+						"color":   "red",
+						"taste":   map[string]any{"sweet": true},
+						"texture": map[string]any{"smooth": true},
+					},
+				},
+			},
+		},
+		{
+			name: "multi-line literal strings",
+			document: "first = '''\nfirst line\n'''\n" +
+				"apostrophe = '''That's it'''\n" +
+				"quoted = ''''That,' she said, 'is still pointless.''''\n" +
+				"regex = '''I [dw]on't need \\d{2} apples'''\n" +
+				"crlf = '''\r\nwindows\r\n'''\n" +
+				"trailing = '''two quotes'''''\n",
+			expected: map[string]any{
+				"first":      "first line\n",
+				"apostrophe": "That's it",
+				"quoted":     "'That,' she said, 'is still pointless.'",
+				"regex":      `I [dw]on't need \d{2} apples`,
+				"crlf":       "windows\r\n",
+				"trailing":   "two quotes''",
+			},
+		},
+		{
+			name:     "inline tables in arrays",
+			document: "points = [ { x = 1 }, { x = 2, y = {} } ]\nempty = {}\n",
+			expected: map[string]any{
+				"points": []any{map[string]any{"x": 1}, map[string]any{"x": 2, "y": map[string]any{}}},
+				"empty":  map[string]any{},
+			},
+		},
+		{
+			name: "dates and times",
+			document: `
+odt = 1979-05-27T07:32:00-08:00
+utc = 1979-05-27 07:32:00Z
+ldt = 1979-05-27T07:32:00.5
+date = 1979-05-27
+time = 07:32:00
+`,
+			expected: map[string]any{
+				"odt":  time.Date(1979, 5, 27, 7, 32, 0, 0, time.FixedZone("", -8*60*60)),
+				"utc":  time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC),
+				"ldt":  time.Date(1979, 5, 27, 7, 32, 0, 500000000, time.UTC),
+				"date": time.Date(1979, 5, 27, 0, 0, 0, 0, time.UTC),
+				"time": "07:32:00",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.document))
+			if err != nil {
+				t.Fatalf("Parse() unexpected error: %v", err)
+			}
+
+			for key, want := range tt.expected {
+				wantTime, isTime := want.(time.Time)
+				if gotTime, ok := got[key].(time.Time); isTime && ok {
+					if !gotTime.Equal(wantTime) {
+						t.Errorf("Parse()[%q] = %v, want %v", key, gotTime, wantTime)
+					}
+
+					got[key] = want
+				}
+			}
+
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Parse() = %#v, want %#v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		line     int
+		message  string
+	}{
+		{name: "duplicate key", document: "a = 1\na = 2", line: 2, message: `key "a" is already defined`},
+		{name: "duplicate table", document: "[a]\nb = 1\n\n[a]", line: 4, message: `table "a" is already defined`},
+		{name: "table over dotted key", document: "[fruit]\napple.color = 'red'\n[fruit.apple]", line: 3, message: "already defined"},
+		{name: "extend inline table", document: "a = { b = 1 }\n[a.c]", line: 2, message: "unable to extend inline table"},
+		{name: "extend static array", document: "a = []\n[[a]]", line: 2, message: "unable to extend static array"},
+		{name: "dotted key into inline table", document: "a = { b = 1 }\na.c = 2", line: 2, message: `key "a" is already defined`},
+		{name: "dotted key into nested inline table", document: "a = { b = { c = 1 } }\na.b.d = 2", line: 2, message: `key "a" is already defined`},
+		{name: "table over inline table", document: "a = { b = 1 }\n[a]", line: 2, message: `table "a" is already defined`},
+		{name: "subtable of inline table", document: "[[a]]\nb = { c = 1 }\n[a.b.d]", line: 3, message: `unable to extend inline table "b"`},
+		{name: "duplicate inline key", document: "a = { b = 1, b = 2 }", line: 1, message: `key "b" is already defined`},
+		{name: "inline key over dotted key", document: "a = { b.c = 1, b = 2 }", line: 1, message: `key "b" is already defined`},
+		{name: "newline in inline table", document: "a = { b = 1,\nc = 2 }", line: 1, message: "expected a key"},
+		{name: "trailing comma in inline table", document: "a = { b = 1, }", line: 1, message: "expected a key"},
+		{name: "table over array of tables", document: "[[a]]\n[a]", line: 2, message: `key "a" is already defined as an array`},
+		{name: "array of tables over table", document: "[a]\n[[a]]", line: 2, message: `key "a" is already defined as a table`},
+		{name: "dotted key into table", document: "[a.b]\nc = 1\n[a]\nb.d = 2", line: 4, message: `key "b" is already defined`},
+		{name: "dotted key over value", document: "a = 1\na.b = 2", line: 2, message: `key "a" is already defined`},
+		{name: "empty dotted key", document: "a..b = 1", line: 1, message: "expected a key"},
+		{name: "too many literal quotes", document: "a = '''abc''''''", line: 1, message: "expected a new line"},
+		{name: "unterminated literal string", document: "a = '''\nabc", line: 2, message: "unterminated multi-line string"},
+		{name: "missing value", document: "a =", line: 1, message: "expected a value"},
+		{name: "unterminated string", document: "a = \"abc\nb = 1", line: 1, message: "unterminated string"},
+		{name: "invalid escape", document: `a = "\q"`, line: 1, message: "invalid escape"},
+		{name: "leading zero", document: "a = 007", line: 1, message: "invalid value"},
+		{name: "two values on a line", document: "a = 1 b = 2", line: 1, message: "expected a new line"},
+		{name: "overflow", document: "a = 9223372036854775808", line: 1, message: "invalid integer"},
+		{name: "bare value", document: "a = yes", line: 1, message: "invalid value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.document))
+
+			var perr *Error
+			if !errors.As(err, &perr) {
+				t.Fatalf("Parse() error = %v, want *Error", err)
+			}
+
+			if perr.Line != tt.line {
+				t.Errorf("Parse() error line = %d, want %d", perr.Line, tt.line)
+			}
+
+			if !strings.Contains(perr.Message, tt.message) {
+				t.Errorf("Parse() error = %q, want it to contain %q", perr.Message, tt.message)
+			}
+		})
+	}
+}
@@ -0,0 +1,379 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/patrickdappollonio/tgen/render"
+	"github.com/patrickdappollonio/tgen/tfuncs"
+	"github.com/spf13/cobra"
+)
+
+// Lint finding kinds
+const (
+	lintSyntax            = "syntax"
+	lintUnknownFunction   = "unknown-function"
+	lintUndefinedTemplate = "undefined-template"
+	lintArgumentCount     = "argument-count"
+)
+
+// builtinFunctions are the functions provided by text/template itself, which
+// are always available and can't be validated through the FuncMap
+var builtinFunctions = map[string]bool{
+	"and": true, "call": true, "html": true, "index": true, "slice": true,
+	"js": true, "len": true, "not": true, "or": true, "print": true,
+	"printf": true, "println": true, "urlquery": true, "eq": true,
+	"ge": true, "gt": true, "le": true, "lt": true, "ne": true,
+}
+
+// lintFinding is a single problem found in a template
+type lintFinding struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+func (f lintFinding) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s", f.File, f.Line, f.Column, f.Kind, f.Message)
+}
+
+type lintFindingsError struct{ count int }
+
+func (e *lintFindingsError) Error() string {
+	if e.count == 1 {
+		return "lint found 1 problem"
+	}
+
+	return fmt.Sprintf("lint found %d problems", e.count)
+}
+
+// lintOptions are the settings used by the lint command
+type lintOptions struct {
+	delimiters string
+	format     string
+	includes   []string
+}
+
+func newLintCommand() *cobra.Command {
+	var opts lintOptions
+
+	cmd := &cobra.Command{
+		Use:   "lint [flags] path...",
+		Short: "statically validate templates without rendering them",
+		Long: "Statically validate templates without rendering them. Reports syntax errors, unknown functions,\n" +
+			"references to undefined templates and function calls with the wrong number of arguments.\n" +
+			"Directories are walked and every text file within them is checked. Templates defined in\n" +
+			"\"_*.tpl\" files within those directories, or in --include paths, can be used by every template.",
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := applyProjectConfig(cmd.Flags(), ""); err != nil {
+				return err
+			}
+
+			return lintCommand(cmd.OutOrStdout(), args, opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.delimiters, "delimiter", "d", "", `template delimiter (default "{{}}")`)
+	cmd.Flags().StringArrayVar(&opts.includes, "include", []string{}, "a file or directory of helper templates, which are linted too (can specify multiple)")
+	cmd.Flags().StringVar(&opts.format, "format", "text", `output format, either "text" or "json"`)
+	return cmd
+}
+
+func lintCommand(w io.Writer, paths []string, opts lintOptions) error {
+	if opts.format != "text" && opts.format != "json" {
+		return fmt.Errorf("unknown lint format %q: valid options are \"text\" or \"json\"", opts.format)
+	}
+
+	// Reuse the same delimiter validation as rendering
+	tg := &tgen{}
+	if opts.delimiters != "" {
+		if err := tg.setDelimiters(opts.delimiters); err != nil {
+			return err
+		}
+	}
+
+	// Helpers are linted like any other template
+	files, err := tg.lintFiles(append(slices.Clone(paths), opts.includes...))
+	if err != nil {
+		return err
+	}
+
+	// Every helper is available to every template
+	for _, include := range opts.includes {
+		if err := tg.loadIncludes(include); err != nil {
+			return err
+		}
+	}
+
+	contents := make(map[string]string, len(files))
+	for _, file := range files {
+		bf, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		contents[file] = string(bf)
+		if isHelperFile(file) {
+			tg.addHelper(file, string(bf))
+		}
+	}
+
+	helpers := make(map[string]*parse.Tree)
+	for _, h := range tg.helpers {
+		// Broken helpers are reported when they're linted themselves
+		tree := parse.New(h.name)
+		tree.Mode = parse.SkipFuncCheck
+		tree.Parse(h.content, tg.preDelimiter, tg.postDelimiter, helpers)
+	}
+
+	findings := []lintFinding{}
+	for _, file := range files {
+		findings = append(findings, lintTemplate(file, contents[file], tg.preDelimiter, tg.postDelimiter, helpers)...)
+	}
+
+	if opts.format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(findings); err != nil {
+			return err
+		}
+	} else {
+		for _, f := range findings {
+			fmt.Fprintln(w, f)
+		}
+	}
+
+	if len(findings) > 0 {
+		return &lintFindingsError{count: len(findings)}
+	}
+
+	return nil
+}
+
+// lintFiles expands the given paths into the list of files to lint,
+// walking directories and skipping the files within them that directory
+// mode wouldn't render with t's delimiters
+func (t *tgen) lintFiles(paths []string) ([]string, error) {
+	var files []string
+
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			files = append(files, p)
+			continue
+		}
+
+		err = filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if d.IsDir() {
+				return nil
+			}
+
+			contents, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+
+			// Only lint files directory mode would render
+			if t.isTemplate(contents) {
+				files = append(files, path)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+// lintFunctions returns the same set of functions available while rendering
+func lintFunctions() template.FuncMap {
+	funcs := render.Functions(tfuncs.Environment{}, false)
+
+	// Templates aren't executed while linting, so only the signature of
+	// "include" matters
+	funcs["include"] = func(string, any) (string, error) { return "", nil }
+	return funcs
+}
+
+// lintTemplate parses a single template and returns every problem found.
+// Templates defined in helpers can be referenced from it.
+func lintTemplate(name, content, leftDelim, rightDelim string, helpers map[string]*parse.Tree) []lintFinding {
+	funcs := lintFunctions()
+
+	// Function checks are skipped while parsing so every unknown function
+	// can be reported, rather than stopping at the first one
+	tree := parse.New(name)
+	tree.Mode = parse.SkipFuncCheck
+	treeSet := make(map[string]*parse.Tree)
+
+	if _, err := tree.Parse(content, leftDelim, rightDelim, treeSet); err != nil {
+		return []lintFinding{syntaxFinding(name, content, leftDelim, err)}
+	}
+
+	l := &linter{funcs: funcs, trees: treeSet, helpers: helpers}
+
+	// Walk trees in a stable order so findings are reproducible
+	names := make([]string, 0, len(treeSet))
+	for n := range treeSet {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	for _, n := range names {
+		l.tree = treeSet[n]
+		l.walk(l.tree.Root)
+	}
+
+	sort.SliceStable(l.findings, func(i, j int) bool {
+		a, b := l.findings[i], l.findings[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+
+		return a.Column < b.Column
+	})
+
+	return l.findings
+}
+
+// syntaxFinding converts a parse error into a finding. Lines and columns
+// are 1-based, like the ones of render.TemplateError.
+func syntaxFinding(name, content, leftDelim string, err error) lintFinding {
+	e := render.NewParseError(name, content, leftDelim, err)
+	return lintFinding{File: name, Line: e.Line, Column: e.Column, Kind: lintSyntax, Message: e.Message}
+}
+
+type linter struct {
+	funcs    template.FuncMap
+	trees    map[string]*parse.Tree
+	helpers  map[string]*parse.Tree
+	tree     *parse.Tree
+	findings []lintFinding
+}
+
+func (l *linter) report(node parse.Node, kind, format string, args ...any) {
+	location, _ := l.tree.ErrorContext(node)
+	f := lintFinding{Kind: kind, Message: fmt.Sprintf(format, args...)}
+	f.File, f.Line, f.Column = render.SplitLocation(location)
+	l.findings = append(l.findings, f)
+}
+
+func (l *linter) walk(node parse.Node) {
+	switch n := node.(type) {
+	case nil:
+		return
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+
+		for _, child := range n.Nodes {
+			l.walk(child)
+		}
+	case *parse.ActionNode:
+		l.walk(n.Pipe)
+	case *parse.IfNode:
+		l.walkBranch(&n.BranchNode)
+	case *parse.RangeNode:
+		l.walkBranch(&n.BranchNode)
+	case *parse.WithNode:
+		l.walkBranch(&n.BranchNode)
+	case *parse.TemplateNode:
+		_, defined := l.trees[n.Name]
+		_, inHelpers := l.helpers[n.Name]
+		if !defined && !inHelpers {
+			l.report(n, lintUndefinedTemplate, "template %q is not defined", n.Name)
+		}
+
+		l.walk(n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+
+		for i, cmd := range n.Cmds {
+			l.checkCommand(cmd, i > 0)
+		}
+	case *parse.ChainNode:
+		l.walk(n.Node)
+	}
+}
+
+func (l *linter) walkBranch(n *parse.BranchNode) {
+	l.walk(n.Pipe)
+	l.walk(n.List)
+	l.walk(n.ElseList)
+}
+
+// checkCommand validates a single command in a pipeline. When piped is true
+// the command receives the result of the previous command as its last
+// argument.
+func (l *linter) checkCommand(cmd *parse.CommandNode, piped bool) {
+	for _, arg := range cmd.Args {
+		l.walk(arg)
+	}
+
+	ident, ok := cmd.Args[0].(*parse.IdentifierNode)
+	if !ok {
+		return
+	}
+
+	if builtinFunctions[ident.Ident] {
+		return
+	}
+
+	fn, found := l.funcs[ident.Ident]
+	if !found {
+		l.report(ident, lintUnknownFunction, "function %q not defined", ident.Ident)
+		return
+	}
+
+	given := len(cmd.Args) - 1
+	if piped {
+		given++
+	}
+
+	typ := reflect.TypeOf(fn)
+	if typ == nil || typ.Kind() != reflect.Func {
+		return
+	}
+
+	want := typ.NumIn()
+	switch {
+	case typ.IsVariadic() && given < want-1:
+		l.report(ident, lintArgumentCount, "function %q expects at least %s, got %d", ident.Ident, pluralArgs(want-1), given)
+	case !typ.IsVariadic() && given != want:
+		l.report(ident, lintArgumentCount, "function %q expects %s, got %d", ident.Ident, pluralArgs(want), given)
+	}
+}
+
+func pluralArgs(n int) string {
+	if n == 1 {
+		return "1 argument"
+	}
+
+	return fmt.Sprintf("%d arguments", n)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLintTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		delims   string
+		expected []lintFinding
+	}{
+		{
+			name:     "valid template",
+			content:  `{{ define "a" }}{{ .name | lowercase }}{{ end }}{{ template "a" . }} {{ env "HOME" }}`,
+			expected: nil,
+		},
+		{
+			name:    "syntax error",
+			content: "line one\n  {{ .name ",
+			expected: []lintFinding{
+				{File: "tpl", Line: 2, Column: 3, Kind: lintSyntax, Message: "unclosed action"},
+			},
+		},
+		{
+			name:    "every unknown function is reported",
+			content: "{{ foo }}\n{{ bar 1 }}",
+			expected: []lintFinding{
+				{File: "tpl", Line: 1, Column: 4, Kind: lintUnknownFunction, Message: `function "foo" not defined`},
+				{File: "tpl", Line: 2, Column: 4, Kind: lintUnknownFunction, Message: `function "bar" not defined`},
+			},
+		},
+		{
+			name:    "undefined template",
+			content: `{{ template "missing" . }}`,
+			expected: []lintFinding{
+				{File: "tpl", Line: 1, Column: 13, Kind: lintUndefinedTemplate, Message: `template "missing" is not defined`},
+			},
+		},
+		{
+			name:    "wrong argument count",
+			content: `{{ rndstring 1 2 }}{{ "x" | envdefault }}`,
+			expected: []lintFinding{
+				{File: "tpl", Line: 1, Column: 4, Kind: lintArgumentCount, Message: `function "rndstring" expects 1 argument, got 2`},
+				{File: "tpl", Line: 1, Column: 29, Kind: lintArgumentCount, Message: `function "envdefault" expects 2 arguments, got 1`},
+			},
+		},
+		{
+			name:     "piped argument is counted",
+			content:  `{{ "x" | envdefault "KEY" }}`,
+			expected: nil,
+		},
+		{
+			name:     "variadic functions",
+			content:  `{{ sprintf "%s-%s" "a" "b" }}{{ list }}`,
+			expected: nil,
+		},
+		{
+			name:    "custom delimiters",
+			content: `[[ nope ]] {{ ignored }}`,
+			delims:  "[[]]",
+			expected: []lintFinding{
+				{File: "tpl", Line: 1, Column: 4, Kind: lintUnknownFunction, Message: `function "nope" not defined`},
+			},
+		},
+		{
+			name:    "nested in branches",
+			content: `{{ if true }}{{ else }}{{ range list }}{{ with nope }}{{ end }}{{ end }}{{ end }}`,
+			expected: []lintFinding{
+				{File: "tpl", Line: 1, Column: 48, Kind: lintUnknownFunction, Message: `function "nope" not defined`},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tg := &tgen{}
+			if tt.delims != "" {
+				if err := tg.setDelimiters(tt.delims); err != nil {
+					t.Fatalf("setDelimiters() unexpected error: %v", err)
+				}
+			}
+
+			got := lintTemplate("tpl", tt.content, tg.preDelimiter, tg.postDelimiter, nil)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("lintTemplate() = %#v, want %#v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLintCommand(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "ok.txt"), `{{ .name }}`, 0o644)
+	writeTestFile(t, filepath.Join(dir, "sub", "bad.txt"), `{{ nope }}`, 0o644)
+	writeTestFile(t, filepath.Join(dir, "plain.txt"), `no templates`, 0o644)
+
+	var buf bytes.Buffer
+	err := lintCommand(&buf, []string{dir}, lintOptions{format: "json"})
+
+	var lintErr *lintFindingsError
+	if !errors.As(err, &lintErr) || lintErr.count != 1 {
+		t.Fatalf("lintCommand() error = %v, want 1 finding", err)
+	}
+
+	var findings []lintFinding
+	if err := json.Unmarshal(buf.Bytes(), &findings); err != nil {
+		t.Fatalf("lintCommand() produced invalid JSON: %v", err)
+	}
+
+	if len(findings) != 1 || findings[0].File != filepath.Join(dir, "sub", "bad.txt") {
+		t.Errorf("lintCommand() findings = %v", findings)
+	}
+
+	buf.Reset()
+	if err := lintCommand(&buf, []string{filepath.Join(dir, "ok.txt")}, lintOptions{format: "text"}); err != nil {
+		t.Errorf("lintCommand() unexpected error: %v", err)
+	}
+
+	if buf.Len() != 0 {
+		t.Errorf("lintCommand() output = %q, want empty", buf.String())
+	}
+}
+
+func TestLintCommandDelimiters(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "bad.txt"), `[[ nope ]]`, 0o644)
+	writeTestFile(t, filepath.Join(dir, "braces.txt"), `{{ not rendered with these delimiters }}`, 0o644)
+
+	var buf bytes.Buffer
+	err := lintCommand(&buf, []string{dir}, lintOptions{format: "json", delimiters: "[[]]"})
+
+	var lintErr *lintFindingsError
+	if !errors.As(err, &lintErr) || lintErr.count != 1 {
+		t.Fatalf("lintCommand() error = %v, want 1 finding", err)
+	}
+
+	var findings []lintFinding
+	if err := json.Unmarshal(buf.Bytes(), &findings); err != nil {
+		t.Fatalf("lintCommand() produced invalid JSON: %v", err)
+	}
+
+	if len(findings) != 1 || findings[0].File != filepath.Join(dir, "bad.txt") || findings[0].Kind != lintUnknownFunction {
+		t.Errorf("lintCommand() findings = %v, want an unknown function in bad.txt", findings)
+	}
+}
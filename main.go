@@ -1,9 +1,13 @@
 package main
 
 import (
+	"fmt"
 	"os"
+	"os/signal"
 
+	"github.com/patrickdappollonio/tgen/internal/setflags"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 const appName = "tgen"
@@ -18,7 +22,6 @@ func main() {
 
 func run() error {
 	var configs conf
-	var withValues bool
 
 	root := &cobra.Command{
 		Use:          appName,
@@ -26,25 +29,97 @@ func run() error {
 		Version:      version,
 		SilenceUsage: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if withValues {
-				configs.valuesFile = "values.yaml"
+			if err := applyProjectConfig(cmd.Flags(), ""); err != nil {
+				return err
 			}
 
-			return command(os.Stdout, configs)
+			return renderCommand(cmd, configs)
 		},
 	}
 
-	root.Flags().StringVarP(&configs.environmentFile, "environment", "e", "", "an optional environment file to use (key=value formatted) to perform replacements")
-	root.Flags().StringVarP(&configs.templateFilePath, "file", "f", "", "the template file to process, or \"-\" to read from stdin")
-	root.Flags().StringVarP(&configs.customDelimiters, "delimiter", "d", "", `template delimiter (default "{{}}")`)
-	root.Flags().StringVarP(&configs.stdinTemplateFile, "execute", "x", "", "a raw template to execute directly, without providing --file")
-	root.Flags().StringVarP(&configs.valuesFile, "values", "v", "", "a file containing values to use for the template, a la Helm")
-	root.Flags().BoolVar(&withValues, "with-values", false, "automatically include a values.yaml file from the current working directory")
-	root.Flags().BoolVarP(&configs.strictMode, "strict", "s", false, "strict mode: if an environment variable or value is used in the template but not set, it fails rendering")
-	root.Flags().StringArrayVar(&configs.setValues, "set", []string{}, "set values on the command line (can specify multiple or separate values with commas: key1=val1,key2=val2)")
-	root.Flags().StringArrayVar(&configs.setStringValues, "set-string", []string{}, "set STRING values on the command line (can specify multiple or separate values with commas: key1=val1,key2=val2)")
+	addInputFlags(root.Flags(), &configs)
+	addRenderFlags(root.Flags(), &configs)
 
 	root.Flags().SortFlags = false
+	root.AddCommand(newLintCommand())
+	root.AddCommand(newInspectCommand())
+	root.AddCommand(newRunCommand())
+	root.AddCommand(newStateCommand())
 
 	return root.Execute()
 }
+
+// renderCommand renders the configured templates once, or keeps rendering
+// them on changes when --watch is set
+func renderCommand(cmd *cobra.Command, configs conf) error {
+	switch configs.errorFormat {
+	case "", errorFormatText:
+	case errorFormatJSON:
+		// Errors are written as JSON, so cobra mustn't print them again
+		cmd.SilenceErrors = true
+	default:
+		return fmt.Errorf("unknown error format %q: valid options are %q or %q", configs.errorFormat, errorFormatText, errorFormatJSON)
+	}
+
+	var err error
+	if configs.watch {
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer stop()
+
+		err = watchCommand(ctx, os.Stdout, os.Stderr, configs)
+	} else {
+		err = command(os.Stdout, configs)
+	}
+
+	if err != nil && configs.errorFormat == errorFormatJSON {
+		writeError(cmd.ErrOrStderr(), err, configs.errorFormat)
+	}
+
+	return err
+}
+
+// addInputFlags registers the flags used to load a template, its values and
+// its environment, shared by every command that needs them
+func addInputFlags(flags *pflag.FlagSet, configs *conf) {
+	flags.StringArrayVarP(&configs.environmentFiles, "environment", "e", []string{}, "an optional environment file to use (key=value formatted) to perform replacements (can specify multiple, later files take precedence)")
+	flags.BoolVar(&configs.envCaseSensitive, "env-case-sensitive", false, "look environment variables up by their exact name, instead of uppercasing them and the keys of environment files")
+	flags.StringVar(&configs.envPrecedence, "env-precedence", envPrecedenceOS, `which environment variables win when set in both places: "os" for the OS environment or "files" for the environment files`)
+	flags.StringVarP(&configs.templateFilePath, "file", "f", "", "the template file to process, or \"-\" to read from stdin")
+	flags.StringVarP(&configs.customDelimiters, "delimiter", "d", "", `template delimiter (default "{{}}")`)
+	flags.StringVarP(&configs.stdinTemplateFile, "execute", "x", "", "a raw template to execute directly, without providing --file")
+	flags.StringArrayVar(&configs.includes, "include", []string{}, "a file or directory of helper templates whose definitions can be used with \"template\" or \"include\" (can specify multiple)")
+	flags.StringArrayVarP(&configs.valuesFiles, "values", "v", []string{}, "a file containing values to use for the template, a la Helm (can specify multiple, later files take precedence)")
+	flags.StringVar(&configs.valuesFormat, "values-format", "", `the format of every values file: "yaml", "json", "toml", "dotenv" or "properties" (default: detected from each file's extension)`)
+	flags.BoolVar(&configs.withValues, "with-values", false, "automatically include a values.yaml file from the current working directory")
+	flags.StringVar(&configs.schemaFile, "schema", "", "a JSON schema file to validate the merged values against (default \"values.schema.json\" next to the first values file, if present)")
+	flags.BoolVar(&configs.skipSchemaValidation, "skip-schema-validation", false, "don't validate values against an automatically detected values.schema.json file")
+	flags.Var(&setFlagValue{setflags.KindSet, &configs.setFlags}, "set", "set values on the command line (can specify multiple or separate values with commas: key1=val1,key2=val2)")
+	flags.Var(&setFlagValue{setflags.KindString, &configs.setFlags}, "set-string", "set STRING values on the command line (can specify multiple or separate values with commas: key1=val1,key2=val2)")
+	flags.Var(&setFlagValue{setflags.KindFile, &configs.setFlags}, "set-file", "set values on the command line from the contents of a file (can specify multiple or separate values with commas: key1=path1,key2=path2)")
+	flags.Var(&setFlagValue{setflags.KindJSON, &configs.setFlags}, "set-json", "set JSON values on the command line (can specify multiple or separate values with commas: key1=jsonval1,key2=jsonval2)")
+}
+
+// addRenderFlags registers the flags that control how and where templates
+// are rendered, shared by the root and "run" commands
+func addRenderFlags(flags *pflag.FlagSet, configs *conf) {
+	flags.BoolVarP(&configs.strictMode, "strict", "s", false, "strict mode: if an environment variable or value is used in the template but not set, it fails rendering")
+	flags.StringVar(&configs.errorFormat, "error-format", errorFormatText, `how errors are printed: "text", or "json" for an array of objects with each error's kind, template, line, column, message and source snippet`)
+	flags.BoolVar(&configs.collectErrors, "collect-errors", false, "in strict mode, keep rendering past missing values, environment variables and failed \"required\" calls, and report all of them at once")
+	flags.StringVarP(&configs.outputFile, "output", "o", "", "write the rendered template to this file instead of stdout, replacing it atomically and only if its contents changed")
+	flags.StringVar(&configs.splitOutput, "split-output", "", "parse the rendered output as multi-document YAML and write each document to its own file in this directory")
+	flags.StringVar(&configs.splitName, "split-name", defaultSplitName, "a template, executed with each document as its values, that names the files written by --split-output")
+	flags.StringVar(&configs.inputDir, "input-dir", "", "a directory of templates to render, mirroring its structure into --output-dir")
+	flags.StringVar(&configs.outputDir, "output-dir", "", "the directory where rendered files from --input-dir are written")
+	flags.BoolVarP(&configs.watch, "watch", "w", false, "watch the template, values, environment and any file read by the template, and re-render on changes")
+	flags.DurationVar(&configs.watchInterval, "watch-interval", defaultWatchInterval, "how often to check for changes when using --watch")
+	flags.BoolVar(&configs.hermetic, "hermetic", false, "render using only declared inputs: environment variables only come from --environment files, files can only be read within the template, --input-dir and --include directories, and random and time functions use --seed and --clock")
+	flags.Var(&seedFlagValue{&configs.seed}, "seed", "the seed for every random function, like \"rndstring\", \"uuidv4\" or \"randInt\", so renders are reproducible (default: random, or 0 in hermetic mode)")
+	flags.StringVar(&configs.clock, "clock", "", `the current time for time functions in hermetic mode, in RFC 3339 format or as seconds since the Unix epoch (default "1970-01-01T00:00:00Z")`)
+	flags.BoolVar(&configs.sandbox, "sandbox", false, "disable every template function that reads files, environment variables, randomness, the clock, cryptographic keys or the network, unless allowed with --allow-capability or --allow-func")
+	flags.StringArrayVar(&configs.allowCapabilities, "allow-capability", []string{}, `in sandbox mode, allow the functions of a capability: "fs", "env", "random", "time", "crypto" or "net" (can specify multiple or separate them with commas)`)
+	flags.StringArrayVar(&configs.denyCapabilities, "deny-capability", []string{}, "disable the functions of a capability, with or without sandbox mode (can specify multiple or separate them with commas)")
+	flags.StringArrayVar(&configs.allowFunctions, "allow-func", []string{}, "allow a function disabled by its capability (can specify multiple or separate them with commas)")
+	flags.StringArrayVar(&configs.denyFunctions, "deny-func", []string{}, "disable a function, regardless of its capability (can specify multiple or separate them with commas)")
+	flags.StringVar(&configs.stateFile, "state", "", "a JSON file where \"persistent\" stores generated values, like passwords, so later renders reuse them")
+	flags.StringArrayVar(&configs.allowRead, "allow-read", []string{}, "only allow the file and directory functions to read from this directory, in addition to the working directory for the \"readlocal\" functions (can specify multiple)")
+}
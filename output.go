@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// defaultOutputMode is the mode used for output files that don't exist yet
+// and for which no explicit mode was requested.
+const defaultOutputMode fs.FileMode = 0o644
+
+// writeFileIfChanged atomically replaces the file at path with data. The
+// contents are first written to a temporary file in the same directory,
+// which is then renamed over the destination, so readers never observe a
+// partially written file. If the file already holds the exact same contents
+// it's left untouched, preserving its modification time.
+//
+// When mode is zero, the mode of the existing file is kept, or
+// defaultOutputMode is used for new files.
+func writeFileIfChanged(path string, data []byte, mode fs.FileMode) error {
+	current, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+
+		if mode == 0 {
+			mode = info.Mode().Perm()
+		}
+
+		if bytes.Equal(current, data) {
+			if info.Mode().Perm() != mode {
+				return os.Chmod(path, mode)
+			}
+
+			return nil
+		}
+
+	case errors.Is(err, fs.ErrNotExist):
+		if mode == 0 {
+			mode = defaultOutputMode
+		}
+
+	default:
+		return err
+	}
+
+	return writeFileAtomic(path, data, mode)
+}
+
+// writeFileAtomic writes data into a temporary file next to path and renames
+// it into place once fully written. The temporary file is removed on error.
+func writeFileAtomic(path string, data []byte, mode fs.FileMode) error {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+base+".tmp-*")
+	if err != nil {
+		return err
+	}
+
+	// Cleanup is a no-op once the file has been renamed
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	// Temporary files are created with 0600, so the final mode has to
+	// be set explicitly before the rename
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteFileIfChanged(t *testing.T) {
+	t.Run("new file gets default mode", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "out.txt")
+
+		if err := writeFileIfChanged(path, []byte("hello"), 0); err != nil {
+			t.Fatalf("writeFileIfChanged() unexpected error: %v", err)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("unable to stat output file: %v", err)
+		}
+
+		if info.Mode().Perm() != defaultOutputMode {
+			t.Errorf("output mode = %v, want %v", info.Mode().Perm(), defaultOutputMode)
+		}
+	})
+
+	t.Run("existing mode is kept", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "out.sh")
+		writeTestFile(t, path, "old", 0o755)
+
+		if err := writeFileIfChanged(path, []byte("new"), 0); err != nil {
+			t.Fatalf("writeFileIfChanged() unexpected error: %v", err)
+		}
+
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("unable to read output file: %v", err)
+		}
+
+		if string(got) != "new" {
+			t.Errorf("output content = %q, want %q", got, "new")
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("unable to stat output file: %v", err)
+		}
+
+		if info.Mode().Perm() != 0o755 {
+			t.Errorf("output mode = %v, want %v", info.Mode().Perm(), os.FileMode(0o755))
+		}
+	})
+
+	t.Run("unchanged content is not rewritten", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "out.txt")
+		writeTestFile(t, path, "same", 0o644)
+
+		past := time.Now().Add(-time.Hour).Truncate(time.Second)
+		if err := os.Chtimes(path, past, past); err != nil {
+			t.Fatalf("unable to change file times: %v", err)
+		}
+
+		if err := writeFileIfChanged(path, []byte("same"), 0); err != nil {
+			t.Fatalf("writeFileIfChanged() unexpected error: %v", err)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("unable to stat output file: %v", err)
+		}
+
+		if !info.ModTime().Equal(past) {
+			t.Errorf("output file was rewritten: mtime = %v, want %v", info.ModTime(), past)
+		}
+	})
+
+	t.Run("no temporary files left behind", func(t *testing.T) {
+		dir := t.TempDir()
+
+		if err := writeFileIfChanged(filepath.Join(dir, "out.txt"), []byte("hello"), 0); err != nil {
+			t.Fatalf("writeFileIfChanged() unexpected error: %v", err)
+		}
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatalf("unable to read directory: %v", err)
+		}
+
+		if len(entries) != 1 {
+			t.Errorf("directory has %d entries, want 1", len(entries))
+		}
+	})
+}
+
+func TestCommandOutputFileUntouchedOnError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.txt")
+	writeTestFile(t, path, "previous", 0o644)
+
+	err := command(nil, conf{stdinTemplateFile: "{{ .missing.key }}", strictMode: true, outputFile: path})
+	if err == nil {
+		t.Fatal("command() expected error but got none")
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unable to read output file: %v", err)
+	}
+
+	if string(got) != "previous" {
+		t.Errorf("output file content = %q, want %q", got, "previous")
+	}
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// projectConfigFile is the name of the project configuration file, looked
+// up from the working directory towards the root of the filesystem
+const projectConfigFile = ".tgen.yaml"
+
+// projectConfigJobs is the key within the project configuration that
+// holds the named render jobs
+const projectConfigJobs = "jobs"
+
+// jobFlags maps the keys of a job to the flags they set
+var jobFlags = map[string]string{
+	"template": "file",
+	"values":   "values",
+	"env":      "environment",
+	"output":   "output",
+}
+
+// pathFlags are the flags whose values are paths. When set from the project
+// configuration, relative paths are resolved from the configuration's
+// directory rather than the working directory.
+var pathFlags = map[string]bool{
+	"environment":  true,
+	"file":         true,
+	"include":      true,
+	"values":       true,
+	"schema":       true,
+	"output":       true,
+	"split-output": true,
+	"input-dir":    true,
+	"output-dir":   true,
+	"allow-read":   true,
+	"state":        true,
+}
+
+// exclusiveFlags are groups of flags that can't be used together. A setting
+// from the project configuration is ignored when another flag of its group
+// was already set, so "-x" on the command line overrides a configured
+// "file", for example.
+var exclusiveFlags = [][]string{
+	{"file", "execute", "input-dir"},
+	{"output", "split-output", "output-dir"},
+}
+
+// projectConfig is a parsed project configuration file. Every key outside
+// of "jobs" is named after the command line flag it provides a default for.
+type projectConfig struct {
+	path     string
+	defaults map[string]any
+	jobs     map[string]map[string]any
+}
+
+// findProjectConfig looks for a project configuration file in dir and each
+// of its parents, returning an empty string if there's none
+func findProjectConfig(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		candidate := filepath.Join(dir, projectConfigFile)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+
+		dir = parent
+	}
+}
+
+// loadProjectConfig parses and validates the project configuration file at
+// path. Unknown keys, and values that the matching flags don't accept, are
+// reported as errors.
+func loadProjectConfig(path string) (*projectConfig, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read project config %q: %w", path, err)
+	}
+
+	var raw map[string]any
+	if err := yaml.Unmarshal(contents, &raw); err != nil {
+		return nil, fmt.Errorf("unable to parse project config %q: %w", path, err)
+	}
+
+	p := &projectConfig{
+		path:     path,
+		defaults: raw,
+		jobs:     make(map[string]map[string]any),
+	}
+
+	if jobs, found := raw[projectConfigJobs]; found {
+		delete(p.defaults, projectConfigJobs)
+
+		jobsMap, ok := jobs.(map[string]any)
+		if !ok && jobs != nil {
+			return nil, fmt.Errorf("invalid project config %q: %q must be a map of job names to jobs", path, projectConfigJobs)
+		}
+
+		for name, job := range jobsMap {
+			jobMap, ok := job.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("invalid project config %q: job %q must be a map", path, name)
+			}
+
+			settings := make(map[string]any, len(jobMap))
+			for key, value := range jobMap {
+				flag, found := jobFlags[key]
+				if !found {
+					return nil, fmt.Errorf("invalid project config %q: unknown key %q in job %q: valid keys are %s", path, key, name, quotedKeys(jobFlags))
+				}
+
+				settings[flag] = value
+			}
+
+			p.jobs[name] = settings
+		}
+	}
+
+	// Validate every setting against the flags of a full render command,
+	// so mistakes are reported regardless of the command being run
+	flags := renderFlagSet()
+	for key := range p.defaults {
+		if flags.Lookup(key) == nil {
+			return nil, fmt.Errorf("invalid project config %q: unknown key %q", path, key)
+		}
+	}
+
+	for _, settings := range append([]map[string]any{p.defaults}, slices.Collect(maps.Values(p.jobs))...) {
+		for _, group := range exclusiveFlags {
+			var set []string
+			for _, name := range group {
+				if _, found := settings[name]; found {
+					set = append(set, name)
+				}
+			}
+
+			if len(set) > 1 {
+				return nil, fmt.Errorf("invalid project config %q: %w", path, &conflictingArgsError{set[0], set[1]})
+			}
+		}
+	}
+
+	if err := p.apply(flags, p.defaults); err != nil {
+		return nil, err
+	}
+
+	for name, job := range p.jobs {
+		if err := p.apply(renderFlagSet(), job); err != nil {
+			return nil, fmt.Errorf("job %q: %w", name, err)
+		}
+	}
+
+	return p, nil
+}
+
+// renderFlagSet returns a new set of the flags accepted by the render
+// commands, bound to a throwaway configuration
+func renderFlagSet() *pflag.FlagSet {
+	var configs conf
+	flags := pflag.NewFlagSet(appName, pflag.ContinueOnError)
+	addInputFlags(flags, &configs)
+	addRenderFlags(flags, &configs)
+	return flags
+}
+
+// apply sets every flag in settings that wasn't given on the command line.
+// Settings for flags that don't exist in flags are ignored, since not every
+// command accepts every flag.
+func (p *projectConfig) apply(flags *pflag.FlagSet, settings map[string]any) error {
+	for name, value := range settings {
+		flag := flags.Lookup(name)
+		if flag == nil || flag.Changed || value == nil || exclusiveFlagChanged(flags, name) {
+			continue
+		}
+
+		items, isList := value.([]any)
+		if !isList {
+			items = []any{value}
+		} else if flag.Value.Type() != "stringArray" {
+			return fmt.Errorf("invalid project config %q: %q must be a single value, not a list", p.path, name)
+		}
+
+		for _, item := range items {
+			switch item.(type) {
+			case map[string]any, []any, nil:
+				return fmt.Errorf("invalid project config %q: %q must be a string, number or boolean", p.path, name)
+			}
+
+			s := fmt.Sprint(item)
+			if pathFlags[name] && s != "-" && !filepath.IsAbs(s) {
+				s = filepath.Join(filepath.Dir(p.path), s)
+			}
+
+			if err := flags.Set(name, s); err != nil {
+				return fmt.Errorf("invalid project config %q: %w", p.path, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// exclusiveFlagChanged reports whether a flag that can't be used together
+// with name was already set
+func exclusiveFlagChanged(flags *pflag.FlagSet, name string) bool {
+	for _, group := range exclusiveFlags {
+		if !slices.Contains(group, name) {
+			continue
+		}
+
+		for _, other := range group {
+			if other != name && flags.Changed(other) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// applyProjectConfig finds the project configuration for the working
+// directory, if any, and uses it to fill in the flags not set on the
+// command line. When job is set, the job's settings take precedence over
+// the configuration defaults, and the configuration must exist.
+func applyProjectConfig(flags *pflag.FlagSet, job string) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	path, err := findProjectConfig(wd)
+	if err != nil {
+		return err
+	}
+
+	if path == "" {
+		if job != "" {
+			return fmt.Errorf("unable to run job %q: no %s file found in %q or any of its parents", job, projectConfigFile, wd)
+		}
+
+		return nil
+	}
+
+	p, err := loadProjectConfig(path)
+	if err != nil {
+		return err
+	}
+
+	if job != "" {
+		settings, found := p.jobs[job]
+		if !found {
+			return p.unknownJobError(job)
+		}
+
+		if err := p.apply(flags, settings); err != nil {
+			return fmt.Errorf("job %q: %w", job, err)
+		}
+	}
+
+	return p.apply(flags, p.defaults)
+}
+
+func (p *projectConfig) unknownJobError(job string) error {
+	if len(p.jobs) == 0 {
+		return fmt.Errorf("unknown job %q: no jobs are defined in %q", job, p.path)
+	}
+
+	names := make([]string, 0, len(p.jobs))
+	for name := range p.jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return fmt.Errorf("unknown job %q: jobs defined in %q are: %s", job, p.path, strings.Join(names, ", "))
+}
+
+// quotedKeys returns the sorted keys of m, quoted and separated by commas
+func quotedKeys(m map[string]string) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, fmt.Sprintf("%q", k))
+	}
+	sort.Strings(keys)
+
+	return strings.Join(keys, ", ")
+}
+
+func newRunCommand() *cobra.Command {
+	var configs conf
+
+	cmd := &cobra.Command{
+		Use:   "run job",
+		Short: "render a job defined in the " + projectConfigFile + " project configuration",
+		Long: "Render a named job from the " + projectConfigFile + " file found in the working directory or any\n" +
+			"of its parents. Flags given on the command line take precedence over the job's settings,\n" +
+			"which take precedence over the defaults set at the top of the configuration file.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := applyProjectConfig(cmd.Flags(), args[0]); err != nil {
+				return err
+			}
+
+			configs.job = args[0]
+			return renderCommand(cmd, configs)
+		},
+	}
+
+	addInputFlags(cmd.Flags(), &configs)
+	addRenderFlags(cmd.Flags(), &configs)
+	cmd.Flags().SortFlags = false
+	return cmd
+}
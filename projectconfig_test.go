@@ -0,0 +1,200 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+func TestFindProjectConfig(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "a", "b")
+	writeTestFile(t, filepath.Join(root, projectConfigFile), "strict: true\n", 0o644)
+
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatalf("unable to create directory: %v", err)
+	}
+
+	got, err := findProjectConfig(nested)
+	if err != nil {
+		t.Fatalf("findProjectConfig() unexpected error: %v", err)
+	}
+
+	if expected := filepath.Join(root, projectConfigFile); got != expected {
+		t.Errorf("findProjectConfig() = %q, want %q", got, expected)
+	}
+}
+
+// parseRenderFlags parses args with the render flags, returning the
+// resulting configuration and flag set
+func parseRenderFlags(t *testing.T, args ...string) (*conf, *pflag.FlagSet) {
+	t.Helper()
+
+	configs := &conf{}
+	flags := pflag.NewFlagSet(appName, pflag.ContinueOnError)
+	addInputFlags(flags, configs)
+	addRenderFlags(flags, configs)
+
+	if err := flags.Parse(args); err != nil {
+		t.Fatalf("unable to parse flags: %v", err)
+	}
+
+	return configs, flags
+}
+
+func TestProjectConfigDefaults(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, projectConfigFile)
+	writeTestFile(t, path, `
+strict: true
+delimiter: "[[]]"
+environment: .env
+values:
+  - base.yaml
+  - prod.yaml
+watch-interval: 2s
+`, 0o644)
+
+	p, err := loadProjectConfig(path)
+	if err != nil {
+		t.Fatalf("loadProjectConfig() unexpected error: %v", err)
+	}
+
+	configs, flags := parseRenderFlags(t, "-d", "<<>>", "-f", "template.txt")
+	if err := p.apply(flags, p.defaults); err != nil {
+		t.Fatalf("apply() unexpected error: %v", err)
+	}
+
+	if !configs.strictMode {
+		t.Error("expected strict mode to be set from the config")
+	}
+
+	if configs.customDelimiters != "<<>>" {
+		t.Errorf("delimiters = %q, want the command line value %q", configs.customDelimiters, "<<>>")
+	}
+
+	if expected := []string{filepath.Join(dir, ".env")}; !reflect.DeepEqual(configs.environmentFiles, expected) {
+		t.Errorf("environment files = %v, want %v", configs.environmentFiles, expected)
+	}
+
+	if expected := []string{filepath.Join(dir, "base.yaml"), filepath.Join(dir, "prod.yaml")}; !reflect.DeepEqual(configs.valuesFiles, expected) {
+		t.Errorf("values files = %v, want %v", configs.valuesFiles, expected)
+	}
+
+	if configs.watchInterval != 2*time.Second {
+		t.Errorf("watch interval = %v, want 2s", configs.watchInterval)
+	}
+
+	if configs.templateFilePath != "template.txt" {
+		t.Errorf("template = %q, want the command line value", configs.templateFilePath)
+	}
+}
+
+func TestProjectConfigJobs(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, projectConfigFile)
+	writeTestFile(t, path, `
+execute: "default"
+output: default.txt
+jobs:
+  web:
+    template: web.tpl
+    values: [web.yaml]
+    env: web.env
+    output: /tmp/web.txt
+`, 0o644)
+
+	p, err := loadProjectConfig(path)
+	if err != nil {
+		t.Fatalf("loadProjectConfig() unexpected error: %v", err)
+	}
+
+	configs, flags := parseRenderFlags(t)
+	if err := p.apply(flags, p.jobs["web"]); err != nil {
+		t.Fatalf("apply() unexpected error: %v", err)
+	}
+
+	if err := p.apply(flags, p.defaults); err != nil {
+		t.Fatalf("apply() unexpected error: %v", err)
+	}
+
+	expected := conf{
+		templateFilePath:  filepath.Join(dir, "web.tpl"),
+		valuesFiles:       []string{filepath.Join(dir, "web.yaml")},
+		environmentFiles:  []string{filepath.Join(dir, "web.env")},
+		envPrecedence:     envPrecedenceOS,
+		errorFormat:       errorFormatText,
+		outputFile:        "/tmp/web.txt",
+		splitName:         defaultSplitName,
+		watchInterval:     defaultWatchInterval,
+		includes:          []string{},
+		allowCapabilities: []string{},
+		denyCapabilities:  []string{},
+		allowFunctions:    []string{},
+		denyFunctions:     []string{},
+		allowRead:         []string{},
+	}
+
+	if !reflect.DeepEqual(*configs, expected) {
+		t.Errorf("configuration = %+v, want %+v", *configs, expected)
+	}
+
+	if err := p.unknownJobError("api"); err == nil || !strings.Contains(err.Error(), "are: web") {
+		t.Errorf("unknownJobError() = %v, want it to list the defined jobs", err)
+	}
+}
+
+func TestProjectConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "unknown key",
+			content: "strcit: true\n",
+			want:    `unknown key "strcit"`,
+		},
+		{
+			name:    "unknown job key",
+			content: "jobs:\n  web:\n    file: web.tpl\n",
+			want:    `unknown key "file" in job "web"`,
+		},
+		{
+			name:    "invalid value",
+			content: "strict: maybe\n",
+			want:    "invalid argument",
+		},
+		{
+			name:    "list for single value",
+			content: "file: [a, b]\n",
+			want:    "must be a single value",
+		},
+		{
+			name:    "conflicting keys",
+			content: "file: a.tpl\nexecute: b\n",
+			want:    "defined both",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), projectConfigFile)
+			writeTestFile(t, path, tt.content, 0o644)
+
+			_, err := loadProjectConfig(path)
+			if err == nil {
+				t.Fatal("loadProjectConfig() expected error but got none")
+			}
+
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("loadProjectConfig() error = %q, want it to contain %q", err.Error(), tt.want)
+			}
+		})
+	}
+}
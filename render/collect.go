@@ -0,0 +1,284 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/patrickdappollonio/tgen/tfuncs"
+)
+
+// Names of the functions that replace the nodes that failed while
+// collecting errors. The nil placeholder is used where the value is
+// checked by "if", "with" or "range", so they behave as if it was unset.
+const (
+	placeholderFunc    = "_tgenPlaceholder"
+	nilPlaceholderFunc = "_tgenNilPlaceholder"
+)
+
+// maxCollectedProblems stops collecting errors from templates that keep
+// failing, like a missing value within an endless include loop
+const maxCollectedProblems = 1000
+
+// StrictReportError is every problem found while rendering a template in
+// strict mode with --collect-errors: missing values, missing environment
+// variables and failed required calls
+type StrictReportError struct {
+	Problems []*TemplateError
+
+	// Stopped, when set, is an unrelated error that stopped rendering
+	// before every problem could be found
+	Stopped error
+}
+
+func (e *StrictReportError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "strict mode on: found %d problem(s) while rendering:", len(e.Problems))
+	for _, p := range e.Problems {
+		fmt.Fprintf(&sb, "\n  - %s", p)
+	}
+
+	if e.Stopped != nil {
+		fmt.Fprintf(&sb, "\nrendering stopped early: %s", e.Stopped)
+	}
+
+	return sb.String()
+}
+
+func (e *StrictReportError) Unwrap() error {
+	return e.Stopped
+}
+
+// placeholderFuncs are the functions used by the placeholders that replace
+// failed nodes
+func placeholderFuncs() template.FuncMap {
+	return template.FuncMap{
+		placeholderFunc:    func(...any) any { return "" },
+		nilPlaceholderFunc: func(...any) any { return nil },
+	}
+}
+
+// executeCollecting executes tpl into out, and every time it fails because
+// of a missing value, a missing environment variable or a failed required
+// call, replaces the failing node with a placeholder and tries again. All
+// the problems found are returned at once, in the order they were found.
+func (r *Renderer) executeCollecting(tpl *template.Template, out *contextWriter) error {
+	report := &StrictReportError{}
+	seen := make(map[string]bool)
+
+	for len(report.Problems) < maxCollectedProblems {
+		out.reset()
+
+		err := tpl.Execute(out, r.values)
+		if err == nil {
+			break
+		}
+
+		kind := renderErrorKind(err)
+		location, _, located := execErrorDetails(err)
+		collectable := kind == ErrorKindMissingKey || kind == ErrorKindMissingEnv || kind == ErrorKindRequired
+
+		if collectable && located && !seen[location] {
+			// The missing value is described before its node is replaced
+			var problem *TemplateError
+			if kind == ErrorKindMissingKey {
+				mv := r.missingValue(tpl, location, err)
+				problem = r.newTemplateError(kind, location, "missing value: "+mv.String(), nil)
+				problem.Suggestions = mv.suggestions
+			} else {
+				problem = r.newTemplateError(kind, location, problemMessage(err), nil)
+			}
+
+			if replaced := replaceFailingNode(tpl, location, kind != ErrorKindMissingKey); replaced != nil {
+				seen[location] = true
+				if !replaced.checked {
+					report.Problems = append(report.Problems, problem)
+				}
+
+				continue
+			}
+		}
+
+		report.Stopped = r.replaceTemplateRenderError(tpl, err)
+		break
+	}
+
+	if len(report.Problems) == 0 {
+		return report.Stopped
+	}
+
+	return report
+}
+
+// problemMessage describes a failed function call collected by
+// executeCollecting
+func problemMessage(err error) string {
+	var envErr tfuncs.ErrVarNotFound
+	var requiredErr tfuncs.ErrRequired
+
+	switch {
+	case errors.As(err, &envErr):
+		return "missing environment variable: $" + string(envErr)
+	case errors.As(err, &requiredErr):
+		return "required value: " + string(requiredErr)
+	}
+
+	return err.Error()
+}
+
+// replaceFailingNode finds the node at location in the templates of tpl and
+// replaces it with a placeholder. When call is set, the node is a function
+// call, and the whole command calling it is replaced. It returns nil if
+// there's no node at location.
+func replaceFailingNode(tpl *template.Template, location string, call bool) *nodeReplacer {
+	for _, tmpl := range tpl.Templates() {
+		if tmpl.Tree == nil || tmpl.Tree.Root == nil {
+			continue
+		}
+
+		r := &nodeReplacer{tree: tmpl.Tree, location: location, call: call}
+		if r.walk(tmpl.Tree.Root) {
+			return r
+		}
+	}
+
+	return nil
+}
+
+// nodeReplacer walks a parse tree looking for the node to replace
+type nodeReplacer struct {
+	tree     *parse.Tree
+	location string
+	call     bool
+
+	// checked is set when the value of the replaced node is checked by a
+	// "required" call
+	checked bool
+}
+
+func (r *nodeReplacer) walk(node parse.Node) bool {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		for _, child := range n.Nodes {
+			if r.walk(child) {
+				return true
+			}
+		}
+	case *parse.ActionNode:
+		return r.pipe(n.Pipe, false)
+	case *parse.IfNode:
+		return r.branch(&n.BranchNode)
+	case *parse.RangeNode:
+		return r.branch(&n.BranchNode)
+	case *parse.WithNode:
+		return r.branch(&n.BranchNode)
+	case *parse.TemplateNode:
+		return r.pipe(n.Pipe, false)
+	}
+
+	return false
+}
+
+func (r *nodeReplacer) branch(b *parse.BranchNode) bool {
+	return r.pipe(b.Pipe, true) || r.walk(b.List) || (b.ElseList != nil && r.walk(b.ElseList))
+}
+
+// pipe looks for the node within the commands of p. When control is set,
+// p is the pipeline checked by "if", "with" or "range".
+func (r *nodeReplacer) pipe(p *parse.PipeNode, control bool) bool {
+	if p == nil {
+		return false
+	}
+
+	for i, cmd := range p.Cmds {
+		// The value of a command piped to "required" is checked by it
+		checked := i+1 < len(p.Cmds) && isRequiredCall(p.Cmds[i+1]) && len(cmd.Args) == 1
+		if !r.command(cmd, control && len(p.Cmds) == 1 && len(cmd.Args) == 1, checked) {
+			continue
+		}
+
+		// The commands the placeholder would be piped to would likely
+		// fail with an unrelated error, so the whole pipeline is replaced
+		if i+1 < len(p.Cmds) && !r.checked && len(cmd.Args) == 1 {
+			name := placeholderFunc
+			if control {
+				name = nilPlaceholderFunc
+			}
+
+			cmd.Args[0] = r.placeholder(name, cmd.Args[0])
+			p.Cmds = p.Cmds[:i+1]
+		}
+
+		return true
+	}
+
+	return false
+}
+
+func (r *nodeReplacer) command(cmd *parse.CommandNode, control, checked bool) bool {
+	for i, arg := range cmd.Args {
+		switch n := arg.(type) {
+		case *parse.PipeNode:
+			if r.pipe(n, false) {
+				return true
+			}
+
+			continue
+		case *parse.ChainNode:
+			// A missing value within "(...).field" makes the whole
+			// chain missing
+			if p, ok := n.Node.(*parse.PipeNode); ok && r.pipe(p, false) {
+				if !r.call {
+					cmd.Args[i] = r.placeholder(placeholderFunc, n)
+				}
+
+				return true
+			}
+		}
+
+		if location, _ := r.tree.ErrorContext(arg); location != r.location {
+			continue
+		}
+
+		switch {
+		case r.call && i == 0:
+			// A failed call is replaced with its result
+			cmd.Args = []parse.Node{r.placeholder(placeholderFunc, arg)}
+		case checked || i > 0 && isRequiredCall(cmd):
+			// A missing value checked by "required" is left for it to
+			// report, so the same problem isn't reported twice
+			r.checked = true
+			cmd.Args[i] = r.placeholder(nilPlaceholderFunc, arg)
+		case i > 0 && isFunctionCall(cmd):
+			// Functions given a missing value would likely fail with
+			// an unrelated error, so the whole call is replaced
+			cmd.Args = []parse.Node{r.placeholder(placeholderFunc, cmd.Args[0])}
+		case control:
+			cmd.Args[i] = r.placeholder(nilPlaceholderFunc, arg)
+		default:
+			cmd.Args[i] = r.placeholder(placeholderFunc, arg)
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// placeholder returns a call to the named placeholder function, at the
+// position of node
+func (r *nodeReplacer) placeholder(name string, node parse.Node) parse.Node {
+	return parse.NewIdentifier(name).SetTree(r.tree).SetPos(node.Position())
+}
+
+func isFunctionCall(cmd *parse.CommandNode) bool {
+	_, ok := cmd.Args[0].(*parse.IdentifierNode)
+	return ok
+}
+
+func isRequiredCall(cmd *parse.CommandNode) bool {
+	ident, ok := cmd.Args[0].(*parse.IdentifierNode)
+	return ok && ident.Ident == "required"
+}
@@ -0,0 +1,125 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCollectErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		helpers  map[string]string
+		expected []string
+		stopped  string
+	}{
+		{
+			name:     "no problems",
+			template: `{{ .Values.name }}`,
+		},
+		{
+			name:     "missing values",
+			template: "{{ .Values.name }}{{ .Values.db.port }}\n{{ $.other.key }}{{ if .flag }}x{{ end }}{{ range .items }}{{ . }}{{ end }}",
+			expected: []string{
+				"missing-key tpl:1:22: missing value: .Values.db.port",
+				"missing-key tpl:2:4: missing value: $.other.key",
+				"missing-key tpl:2:24: missing value: .flag",
+				"missing-key tpl:2:51: missing value: .items",
+			},
+		},
+		{
+			name:     "missing environment variables",
+			template: `{{ env "TGEN_TEST_UNSET_ONE" }}{{ env "tgen_test_unset_two" | upper }}`,
+			expected: []string{
+				"missing-env tpl:1:4: missing environment variable: $TGEN_TEST_UNSET_ONE",
+				"missing-env tpl:1:35: missing environment variable: $TGEN_TEST_UNSET_TWO",
+			},
+		},
+		{
+			name:     "failed required calls report the value once",
+			template: `{{ required "token is required" .token }}{{ .secret | required "secret is required" }}{{ required "name" .Values.name }}`,
+			expected: []string{
+				"required tpl:1:4: required value: token is required",
+				"required tpl:1:55: required value: secret is required",
+			},
+		},
+		{
+			name:     "missing values given to functions",
+			template: `{{ index .list 1 }}{{ .str | first | upper }}{{ (.nested).deep }}{{ if .mode | eq "x" }}x{{ end }}`,
+			expected: []string{
+				"missing-key tpl:1:10: missing value: .list",
+				"missing-key tpl:1:23: missing value: .str",
+				"missing-key tpl:1:50: missing value: .nested",
+				"missing-key tpl:1:72: missing value: .mode",
+			},
+		},
+		{
+			name:     "missing values within helpers",
+			template: `{{ include "helper" . }}{{ template "helper" . }}{{ .after }}`,
+			helpers:  map[string]string{"_helpers.tpl": "\n{{ define \"helper\" }}{{ .inner }}{{ end }}"},
+			expected: []string{
+				"missing-key _helpers.tpl:2:25: missing value: .inner",
+				"missing-key tpl:1:53: missing value: .after",
+			},
+		},
+		{
+			name:     "unrelated errors stop rendering",
+			template: `{{ .missing }}{{ fail "boom" }}{{ .never }}`,
+			expected: []string{
+				"missing-key tpl:1:4: missing value: .missing",
+			},
+			stopped: "boom",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := []Option{
+				WithTemplate("tpl", tt.template),
+				WithValues(map[string]any{"name": "app", "db": map[string]any{}}),
+				WithStrict(true),
+				WithCollectErrors(true),
+			}
+
+			for name, content := range tt.helpers {
+				opts = append(opts, WithHelper(name, content))
+			}
+
+			err := New(opts...).Render(context.Background(), &bytes.Buffer{})
+			if tt.expected == nil {
+				if err != nil {
+					t.Fatalf("render() unexpected error: %v", err)
+				}
+
+				return
+			}
+
+			var report *StrictReportError
+			if !errors.As(err, &report) {
+				t.Fatalf("render() error = %v, want a strict report", err)
+			}
+
+			var got []string
+			for _, p := range report.Problems {
+				got = append(got, fmt.Sprintf("%s %s", p.Kind, p))
+			}
+
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("render() problems = %q, want %q", got, tt.expected)
+			}
+
+			if tt.stopped == "" && report.Stopped != nil {
+				t.Errorf("render() stopped early: %v", report.Stopped)
+			}
+
+			if tt.stopped != "" && (report.Stopped == nil || !strings.Contains(report.Stopped.Error(), tt.stopped)) {
+				t.Errorf("render() stopped = %v, want it to contain %q", report.Stopped, tt.stopped)
+			}
+		})
+	}
+}
@@ -0,0 +1,359 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/patrickdappollonio/tgen/tfuncs"
+)
+
+// ErrorKind classifies the errors found while rendering
+type ErrorKind string
+
+// Kinds of errors, as reported by --error-format=json
+const (
+	ErrorKindParse      ErrorKind = "parse"
+	ErrorKindMissingKey ErrorKind = "missing-key"
+	ErrorKindMissingEnv ErrorKind = "missing-env"
+	ErrorKindRequired   ErrorKind = "required"
+	ErrorKindFunction   ErrorKind = "function"
+	ErrorKindDisabled   ErrorKind = "disabled"
+	ErrorKindIO         ErrorKind = "io"
+
+	// ErrorKindOther is any error unrelated to a template, like invalid
+	// flags or values files
+	ErrorKindOther ErrorKind = "other"
+)
+
+// TemplateError is an error found while parsing or rendering a template,
+// with its location. Line and Column are zero when the error can't be
+// located. Both are 1-based, and Column counts bytes from the start of the
+// line, like most editors and compilers.
+type TemplateError struct {
+	Kind     ErrorKind `json:"kind"`
+	Template string    `json:"template,omitempty"`
+	Line     int       `json:"line"`
+	Column   int       `json:"column"`
+	Message  string    `json:"message"`
+
+	// Source is the line of the template where the error happened, and
+	// Snippet the same line with a caret under the column
+	Source  string `json:"source,omitempty"`
+	Snippet string `json:"snippet,omitempty"`
+
+	// Suggestions are the paths of existing values close to a missing one
+	Suggestions []string `json:"suggestions,omitempty"`
+
+	err error
+}
+
+func (e *TemplateError) Error() string {
+	if e.err != nil {
+		return e.err.Error()
+	}
+
+	if e.Template == "" {
+		return e.Message
+	}
+
+	return fmt.Sprintf("%s:%d:%d: %s", e.Template, e.Line, e.Column, e.Message)
+}
+
+func (e *TemplateError) Unwrap() error {
+	return e.err
+}
+
+// newTemplateError returns an error of kind at location, a "name:line:col"
+// string as reported by text/template, wrapping err for its message
+func (r *Renderer) newTemplateError(kind ErrorKind, location, message string, err error) *TemplateError {
+	e := &TemplateError{Kind: kind, Message: message, err: err}
+	e.Template, e.Line, e.Column = SplitLocation(location)
+
+	if content, found := r.templateSource(e.Template); found {
+		e.Column = expressionStart(content, e.Line, e.Column)
+		e.Source, e.Snippet = sourceSnippet(content, e.Line, e.Column)
+	}
+
+	return e
+}
+
+// expressionStart moves col back to the start of the expression it's in.
+// text/template reports field chains like ".a.b" or "$.a.b" at their second
+// segment, so without this the column would point in the middle of them.
+func expressionStart(content string, line, col int) int {
+	lines := strings.Split(content, "\n")
+	if line < 1 || line > len(lines) || col < 1 || col > len(lines[line-1]) {
+		return col
+	}
+
+	source := lines[line-1]
+	for col > 1 && isExpressionChar(source[col-2]) {
+		col--
+	}
+
+	return col
+}
+
+// isExpressionChar reports whether c can be part of a field chain
+func isExpressionChar(c byte) bool {
+	return c == '.' || c == '$' || c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// sourceSnippet returns the given line of content, and the same line with
+// a caret under the 1-based col
+func sourceSnippet(content string, line, col int) (string, string) {
+	lines := strings.Split(content, "\n")
+	if line < 1 || line > len(lines) {
+		return "", ""
+	}
+
+	source := strings.TrimSuffix(lines[line-1], "\r")
+	col = max(min(col-1, len(source)), 0)
+
+	// Keep tabs so the caret lines up however tabs are displayed
+	var caret strings.Builder
+	for _, r := range source[:col] {
+		if r == '\t' {
+			caret.WriteRune('\t')
+		} else {
+			caret.WriteRune(' ')
+		}
+	}
+	caret.WriteRune('^')
+
+	return source, source + "\n" + caret.String()
+}
+
+// SplitLocation splits a "name:line:col" location as returned by
+// parse.Tree.ErrorContext into its parts. The column reported by
+// text/template is a 0-based byte offset, and is returned 1-based.
+func SplitLocation(location string) (string, int, int) {
+	rest, colStr := cutLast(location, ":")
+	name, lineStr := cutLast(rest, ":")
+	line, _ := strconv.Atoi(lineStr)
+	col, err := strconv.Atoi(colStr)
+	if err != nil {
+		return name, line, 0
+	}
+
+	return name, line, col + 1
+}
+
+// LocateExpression splits location like SplitLocation, moving the column
+// back to the start of the expression of content it points into
+func LocateExpression(content, location string) (string, int, int) {
+	name, line, col := SplitLocation(location)
+	return name, line, expressionStart(content, line, col)
+}
+
+func cutLast(s, sep string) (string, string) {
+	idx := strings.LastIndex(s, sep)
+	if idx < 0 {
+		return s, ""
+	}
+
+	return s[:idx], s[idx+len(sep):]
+}
+
+// templateSource returns the contents of the main template or the helper
+// with the given name
+func (r *Renderer) templateSource(name string) (string, bool) {
+	if name == r.name {
+		return r.content, true
+	}
+
+	for _, h := range r.helpers {
+		if h.name == name {
+			return h.content, true
+		}
+	}
+
+	return "", false
+}
+
+// reParseError extracts the line number and message from a text/template
+// parse error
+var reParseError = regexp.MustCompile(`^template: .*?:(\d+): (.*)$`)
+
+// NewParseError converts err, an error parsing the named template with the
+// given content and left delimiter, into a TemplateError. The parser only
+// reports the line of the error, so the column points at the first action
+// opened on that line, or the start of the line if there's none.
+func NewParseError(name, content, leftDelim string, err error) *TemplateError {
+	e := &TemplateError{
+		Kind:     ErrorKindParse,
+		Template: name,
+		Line:     1,
+		Column:   1,
+		Message:  err.Error(),
+		err:      fmt.Errorf("unable to parse template file %q: %s", name, err.Error()),
+	}
+
+	if m := reParseError.FindStringSubmatch(err.Error()); m != nil {
+		e.Line, _ = strconv.Atoi(m[1])
+		e.Message = m[2]
+
+		if leftDelim == "" {
+			leftDelim = "{{"
+		}
+
+		lines := strings.Split(content, "\n")
+		if e.Line >= 1 && e.Line <= len(lines) {
+			if idx := strings.Index(lines[e.Line-1], leftDelim); idx >= 0 {
+				e.Column = idx + 1
+			}
+		}
+	}
+
+	e.Source, e.Snippet = sourceSnippet(content, e.Line, e.Column)
+	return e
+}
+
+// parseError converts an error parsing one of the templates of r
+func (r *Renderer) parseError(name, content string, err error) *TemplateError {
+	return NewParseError(name, content, r.leftDelim, err)
+}
+
+// renderErrorKind classifies an error returned while executing a template
+func renderErrorKind(err error) ErrorKind {
+	var envErr tfuncs.ErrVarNotFound
+	var requiredErr tfuncs.ErrRequired
+	var disabledErr tfuncs.ErrFunctionDisabled
+	var pathErr *fs.PathError
+
+	switch {
+	case errors.As(err, &envErr):
+		return ErrorKindMissingEnv
+	case errors.As(err, &requiredErr):
+		return ErrorKindRequired
+	case errors.As(err, &disabledErr):
+		return ErrorKindDisabled
+	case strings.Contains(err.Error(), "map has no entry for key"):
+		return ErrorKindMissingKey
+	case errors.As(err, &pathErr):
+		return ErrorKindIO
+	}
+
+	return ErrorKindFunction
+}
+
+// execErrorDetails returns the innermost location in an error returned while
+// executing a template, and the message that follows it, without the
+// "executing ... at <...>" context
+func execErrorDetails(err error) (string, string, bool) {
+	msg := err.Error()
+
+	matches := reExtractLocation.FindAllStringSubmatchIndex(msg, -1)
+	if len(matches) == 0 {
+		return "", msg, false
+	}
+
+	last := matches[len(matches)-1]
+	location, rest := msg[last[2]:last[3]], msg[last[1]:]
+
+	if idx := strings.Index(rest, ">: "); idx >= 0 {
+		rest = rest[idx+len(">: "):]
+	}
+
+	return location, strings.TrimSpace(rest), true
+}
+
+type templateFuncError struct {
+	line     string
+	original error
+}
+
+func (t templateFuncError) Error() string {
+	if t.line == "" {
+		return t.original.Error()
+	}
+
+	return fmt.Sprintf("evaluating %s: %s", t.line, t.original)
+}
+
+func (t templateFuncError) Unwrap() error {
+	return t.original
+}
+
+type missingKeyErr struct{ name string }
+
+func (e *missingKeyErr) Error() string {
+	return "strict mode on: missing value in values file: " + e.name
+}
+
+// reExtractLocation is used to extract the line number from the error message
+// as a string like "/foo/bar:1:18"
+var reExtractLocation = regexp.MustCompile(`\s([^:]*:\d+:\d+):`)
+
+// replaceTemplateRenderError converts an error returned while executing the
+// template into a TemplateError, when it can be located
+func (r *Renderer) replaceTemplateRenderError(tpl *template.Template, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	simplified := simplifyRenderError(err)
+
+	location, message, located := execErrorDetails(err)
+	if !located {
+		return simplified
+	}
+
+	kind := renderErrorKind(err)
+	if kind != ErrorKindMissingKey {
+		return r.newTemplateError(kind, location, message, simplified)
+	}
+
+	// The error only names the missing key, so the full path is recovered
+	// from the template
+	mv := r.missingValue(tpl, location, err)
+	terr := r.newTemplateError(kind, location, "missing value: "+mv.String(), &missingKeyErr{name: mv.String()})
+	terr.Suggestions = mv.suggestions
+	return terr
+}
+
+// simplifyRenderError returns a more meaningful error for the errors returned
+// while executing the template
+func simplifyRenderError(err error) error {
+	// Go templates won't propagate the error message back to the caller, so the
+	// only way to know what happened is to parse the error message and return
+	// a more meaningful error.
+	if t, ok := err.(template.ExecError); ok {
+
+		// Check if we can unwrap the error bubbled up from the template
+		if unwrap := errors.Unwrap(t.Err); unwrap != nil {
+			// The original error does not provide enough contextual information
+			// to know where the error happened, so we need to extract the line
+			// number from the error message
+			matchExpr := reExtractLocation.FindStringSubmatch(t.Err.Error())
+			match := ""
+			if len(matchExpr) > 0 {
+				match = matchExpr[1]
+			}
+
+			switch unwrap.(type) {
+			case *tfuncs.ErrRequired, *tfuncs.ErrVarNotFound:
+				return &templateFuncError{line: match, original: unwrap}
+			default:
+				// do nothing, the next section will take care
+				// of checking for additional items
+			}
+		}
+
+		// If we can't unwrap, it means we're dealing with string-based errors
+		// which are even harder to validate
+		switch {
+		case strings.Contains(err.Error(), "map has no entry for key"):
+			return &missingKeyErr{name: err.Error()[strings.LastIndex(err.Error(), ":")+2:]}
+		default:
+			return t.Err
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,194 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestTemplateErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		kind     ErrorKind
+		line     int
+		column   int
+		message  string
+		source   string
+		snippet  string
+		legacy   string
+	}{
+		{
+			name:     "parse error",
+			template: "ok\n{{ if .a }}\n",
+			kind:     ErrorKindParse,
+			line:     3,
+			column:   1,
+			message:  "unexpected EOF",
+			legacy:   `unable to parse template file "tpl": template: tpl:3: unexpected EOF`,
+		},
+		{
+			name:     "missing key",
+			template: "a\nport: {{ .db.port }}",
+			kind:     ErrorKindMissingKey,
+			line:     2,
+			column:   10,
+			message:  "missing value: .db.port",
+			source:   "port: {{ .db.port }}",
+			snippet:  "port: {{ .db.port }}\n         ^",
+		},
+		{
+			name:     "missing environment variable",
+			template: "\t{{ env \"TGEN_TEST_MISSING\" }}",
+			kind:     ErrorKindMissingEnv,
+			line:     1,
+			column:   5,
+			source:   "\t{{ env \"TGEN_TEST_MISSING\" }}",
+			snippet:  "\t{{ env \"TGEN_TEST_MISSING\" }}\n\t   ^",
+		},
+		{
+			name:     "required value",
+			template: `{{ required "name is required" "" }}`,
+			kind:     ErrorKindRequired,
+			line:     1,
+			column:   4,
+		},
+		{
+			name:     "failed function",
+			template: `{{ fail "boom" }}`,
+			kind:     ErrorKindFunction,
+			line:     1,
+			column:   4,
+			message:  "error calling fail: boom",
+		},
+		{
+			name:     "unreadable file",
+			template: `{{ readfile "does-not-exist.txt" }}`,
+			kind:     ErrorKindIO,
+			line:     1,
+			column:   4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := New(WithTemplate("tpl", tt.template), WithStrict(true)).Render(context.Background(), &bytes.Buffer{})
+
+			var terr *TemplateError
+			if !errors.As(err, &terr) {
+				t.Fatalf("render() error = %v, want a template error", err)
+			}
+
+			if terr.Kind != tt.kind {
+				t.Errorf("Kind = %q, want %q", terr.Kind, tt.kind)
+			}
+
+			if terr.Template != "tpl" || terr.Line != tt.line || terr.Column != tt.column {
+				t.Errorf("location = %s:%d:%d, want tpl:%d:%d", terr.Template, terr.Line, terr.Column, tt.line, tt.column)
+			}
+
+			if tt.message != "" && terr.Message != tt.message {
+				t.Errorf("Message = %q, want %q", terr.Message, tt.message)
+			}
+
+			if tt.source != "" && terr.Source != tt.source {
+				t.Errorf("Source = %q, want %q", terr.Source, tt.source)
+			}
+
+			if tt.snippet != "" && terr.Snippet != tt.snippet {
+				t.Errorf("Snippet = %q, want %q", terr.Snippet, tt.snippet)
+			}
+
+			if tt.legacy != "" && err.Error() != tt.legacy {
+				t.Errorf("Error() = %q, want %q", err.Error(), tt.legacy)
+			}
+		})
+	}
+}
+
+func TestTemplateErrorLocation(t *testing.T) {
+	values := map[string]any{"db": map[string]any{}}
+
+	tests := []struct {
+		name     string
+		template string
+		delims   []string
+		expected string
+	}{
+		{
+			name:     "field chain",
+			template: "first\nport: {{ .db.port }}",
+			expected: "tpl:2:10: missing value: .db.port",
+		},
+		{
+			name:     "long field chain without spaces",
+			template: "{{.db.conn.port}}",
+			expected: "tpl:1:3: missing value: .db.conn.port",
+		},
+		{
+			name:     "chain from the root variable",
+			template: "a {{ $.db.port }}",
+			expected: "tpl:1:6: missing value: $.db.port",
+		},
+		{
+			name:     "custom delimiters",
+			template: "[[.db.port]]",
+			delims:   []string{"[[", "]]"},
+			expected: "tpl:1:3: missing value: .db.port",
+		},
+		{
+			name:     "function call",
+			template: `{{ "boom" | fail }}`,
+			expected: "tpl:1:13: error calling fail: boom",
+		},
+		{
+			name:     "parse error",
+			template: "ok\n  {{ if .a }}",
+			expected: "tpl:2:3: unexpected EOF",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := []Option{WithTemplate("tpl", tt.template), WithValues(values), WithStrict(true)}
+			if tt.delims != nil {
+				opts = append(opts, WithDelimiters(tt.delims[0], tt.delims[1]))
+			}
+
+			err := New(opts...).Render(context.Background(), &bytes.Buffer{})
+
+			var terr *TemplateError
+			if !errors.As(err, &terr) {
+				t.Fatalf("render() error = %v, want a template error", err)
+			}
+
+			got := fmt.Sprintf("%s:%d:%d: %s", terr.Template, terr.Line, terr.Column, terr.Message)
+			if got != tt.expected {
+				t.Errorf("location = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSplitLocation(t *testing.T) {
+	tests := []struct {
+		location string
+		name     string
+		line     int
+		column   int
+	}{
+		{location: "tpl:2:0", name: "tpl", line: 2, column: 1},
+		{location: "C:\\templates\\a.tpl:10:7", name: "C:\\templates\\a.tpl", line: 10, column: 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.location, func(t *testing.T) {
+			name, line, column := SplitLocation(tt.location)
+			if name != tt.name || line != tt.line || column != tt.column {
+				t.Errorf("SplitLocation() = %q, %d, %d, want %q, %d, %d", name, line, column, tt.name, tt.line, tt.column)
+			}
+		})
+	}
+}
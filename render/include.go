@@ -0,0 +1,51 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"text/template"
+)
+
+// maxIncludeDepth limits how deep "include" calls can be nested, to stop
+// templates that include themselves from running forever
+const maxIncludeDepth = 1000
+
+// parseHelpers parses every helper template into the set of tpl. Each helper
+// keeps its own name, so errors point at the helper's file and line.
+func (r *Renderer) parseHelpers(tpl *template.Template) error {
+	for _, h := range r.helpers {
+		if _, err := tpl.New(h.name).Parse(h.content); err != nil {
+			return r.parseError(h.name, h.content, err)
+		}
+	}
+
+	return nil
+}
+
+// includeFunc returns the "include" template function, which executes a
+// named template from the set of tpl and returns its output as a string,
+// so it can be piped to other functions like "nindent"
+func includeFunc(ctx context.Context, tpl *template.Template) func(name string, data any) (string, error) {
+	depth := 0
+
+	return func(name string, data any) (string, error) {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+
+		if depth >= maxIncludeDepth {
+			return "", fmt.Errorf("unable to include template %q: maximum include depth of %d reached", name, maxIncludeDepth)
+		}
+
+		depth++
+		defer func() { depth-- }()
+
+		var buf bytes.Buffer
+		if err := tpl.ExecuteTemplate(&buf, name, data); err != nil {
+			return "", err
+		}
+
+		return buf.String(), nil
+	}
+}
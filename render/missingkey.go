@@ -0,0 +1,351 @@
+package render
+
+import (
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// maxSuggestions is the maximum number of "did you mean" suggestions given
+// for a missing value
+const maxSuggestions = 3
+
+// reMissingKey extracts the key from the error returned by text/template
+// when a map key is missing, with the "missingkey=error" option
+var reMissingKey = regexp.MustCompile(`map has no entry for key "([^"]*)"`)
+
+// missingValue describes a value used in a template but missing from the
+// values, like ".Values.db.port"
+type missingValue struct {
+	// path is the full field chain of the value, with the fields of the
+	// enclosing "with" blocks when they're known
+	path string
+
+	// suggestions are paths like path, with the missing key replaced with
+	// the closest keys that exist at the same level of the values
+	suggestions []string
+}
+
+func (m missingValue) String() string {
+	if len(m.suggestions) == 0 {
+		return m.path
+	}
+
+	return m.path + ", did you mean " + strings.Join(m.suggestions, " or ") + "?"
+}
+
+// missingValue recovers the missing value reported by err, an error
+// executing tpl, from the node at location. The key in the error message is
+// used when there's no field chain at location.
+func (r *Renderer) missingValue(tpl *template.Template, location string, err error) missingValue {
+	key := ""
+	if m := reMissingKey.FindStringSubmatch(err.Error()); m != nil {
+		key = m[1]
+	}
+
+	found := findNode(tpl, location, key)
+	if found == nil {
+		return missingValue{path: key}
+	}
+
+	fields, base, known := found.fields()
+	if !known {
+		return missingValue{path: found.node.String()}
+	}
+
+	path := append(append([]string{}, base...), fields...)
+	mv := missingValue{path: found.display(path)}
+
+	// Find the first key in the path that's missing from the values, and
+	// suggest the closest keys at that level
+	level := r.values
+	for i, field := range path {
+		next, exists := level[field]
+		if !exists {
+			if field != key {
+				break
+			}
+
+			for _, s := range closestKeys(field, level, i+1 < len(path)) {
+				suggested := append(append([]string{}, path[:i]...), s)
+				mv.suggestions = append(mv.suggestions, found.display(append(suggested, path[i+1:]...)))
+			}
+
+			break
+		}
+
+		if level, exists = next.(map[string]any); !exists {
+			break
+		}
+	}
+
+	return mv
+}
+
+// closestKeys returns the keys of values within a small edit distance of
+// key, closest first. When nested is set, only keys of maps are returned,
+// since more fields follow the key.
+func closestKeys(key string, values map[string]any, nested bool) []string {
+	type candidate struct {
+		key      string
+		distance int
+	}
+
+	// Allow roughly one typo every three characters
+	threshold := max(1, len(key)/3)
+
+	var candidates []candidate
+	for k, v := range values {
+		if _, isMap := v.(map[string]any); nested && !isMap {
+			continue
+		}
+
+		if d := editDistance(strings.ToLower(key), strings.ToLower(k)); d <= threshold && k != key {
+			candidates = append(candidates, candidate{key: k, distance: d})
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+
+		return candidates[i].key < candidates[j].key
+	})
+
+	keys := make([]string, 0, maxSuggestions)
+	for i := 0; i < len(candidates) && i < maxSuggestions; i++ {
+		keys = append(keys, candidates[i].key)
+	}
+
+	return keys
+}
+
+// editDistance returns the optimal string alignment distance between a and
+// b: the edits needed to turn one into the other, where an edit inserts,
+// deletes or replaces a character, or swaps two adjacent ones
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d[len(ra)][len(rb)]
+}
+
+// foundNode is a node found by findNode, with the fields of the values that
+// dot points to where it was found
+type foundNode struct {
+	node  parse.Node
+	scope valueScope
+}
+
+// valueScope is the path of dot within the values, when it's known: dot is
+// the values at the top of the main template, and changes within "with"
+// blocks checking a field chain. Root is set within the main template,
+// where "$" is the values.
+type valueScope struct {
+	path  []string
+	known bool
+	root  bool
+}
+
+// fields returns the field chain of the node, and the path of the values
+// it starts from. It's not known for fields of variables other than "$",
+// of the result of functions or of an unknown dot.
+func (f *foundNode) fields() ([]string, []string, bool) {
+	switch n := f.node.(type) {
+	case *parse.FieldNode:
+		return n.Ident, f.scope.path, f.scope.known
+	case *parse.VariableNode:
+		if n.Ident[0] == "$" && f.scope.root {
+			return n.Ident[1:], nil, true
+		}
+	case *parse.ChainNode:
+		// A chain like "(.a.b).c" continues the fields of its node
+		if inner := f.chained(); inner != nil {
+			if fields, base, known := inner.fields(); known {
+				return append(append([]string{}, fields...), n.Field...), base, true
+			}
+		}
+	}
+
+	return nil, nil, false
+}
+
+// chained returns the node a chain continues, when it's a single field
+// chain or variable within parentheses
+func (f *foundNode) chained() *foundNode {
+	chain, ok := f.node.(*parse.ChainNode)
+	if !ok {
+		return nil
+	}
+
+	p, ok := chain.Node.(*parse.PipeNode)
+	if !ok || len(p.Decl) > 0 || len(p.Cmds) != 1 || len(p.Cmds[0].Args) != 1 {
+		return nil
+	}
+
+	return &foundNode{node: p.Cmds[0].Args[0], scope: f.scope}
+}
+
+// display formats path like the node was written, rooted at "$" for
+// variables and at dot otherwise
+func (f *foundNode) display(path []string) string {
+	root := f
+	for inner := root.chained(); inner != nil; inner = root.chained() {
+		root = inner
+	}
+
+	if _, ok := root.node.(*parse.VariableNode); ok {
+		return "$." + strings.Join(path, ".")
+	}
+
+	return "." + strings.Join(path, ".")
+}
+
+// has reports whether key is one of the fields of the node
+func (f *foundNode) has(key string) bool {
+	switch n := f.node.(type) {
+	case *parse.FieldNode:
+		return slices.Contains(n.Ident, key)
+	case *parse.VariableNode:
+		return slices.Contains(n.Ident[1:], key)
+	case *parse.ChainNode:
+		return slices.Contains(n.Field, key)
+	}
+
+	return false
+}
+
+// findNode returns the node at location in the templates of tpl that's
+// missing key, or nil if there's none
+func findNode(tpl *template.Template, location, key string) *foundNode {
+	for _, tmpl := range tpl.Templates() {
+		if tmpl.Tree == nil || tmpl.Tree.Root == nil {
+			continue
+		}
+
+		// Helpers and defined templates can be called with any data
+		isMain := tmpl.Name() == tpl.Name()
+		f := &nodeFinder{tree: tmpl.Tree, location: location, key: key}
+		if f.walk(tmpl.Tree.Root, valueScope{known: isMain, root: isMain}) {
+			return &f.found
+		}
+	}
+
+	return nil
+}
+
+// nodeFinder walks a parse tree looking for the node at a location, keeping
+// track of where dot points to
+type nodeFinder struct {
+	tree     *parse.Tree
+	location string
+	key      string
+	found    foundNode
+}
+
+func (f *nodeFinder) walk(node parse.Node, scope valueScope) bool {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		for _, child := range n.Nodes {
+			if f.walk(child, scope) {
+				return true
+			}
+		}
+	case *parse.ActionNode:
+		return f.pipe(n.Pipe, scope)
+	case *parse.IfNode:
+		return f.branch(&n.BranchNode, scope, scope)
+	case *parse.RangeNode:
+		return f.branch(&n.BranchNode, valueScope{root: scope.root}, scope)
+	case *parse.WithNode:
+		return f.branch(&n.BranchNode, withScope(n.Pipe, scope), scope)
+	case *parse.TemplateNode:
+		return f.pipe(n.Pipe, scope)
+	}
+
+	return false
+}
+
+// branch looks for the node in b, where inner is the scope of its list and
+// scope the one of its pipeline and "else" list
+func (f *nodeFinder) branch(b *parse.BranchNode, inner, scope valueScope) bool {
+	return f.pipe(b.Pipe, scope) || f.walk(b.List, inner) || (b.ElseList != nil && f.walk(b.ElseList, scope))
+}
+
+func (f *nodeFinder) pipe(p *parse.PipeNode, scope valueScope) bool {
+	if p == nil {
+		return false
+	}
+
+	for _, cmd := range p.Cmds {
+		for _, arg := range cmd.Args {
+			if location, _ := f.tree.ErrorContext(arg); location == f.location {
+				f.found = foundNode{node: arg, scope: scope}
+				return true
+			}
+
+			switch n := arg.(type) {
+			case *parse.PipeNode:
+				if f.pipe(n, scope) {
+					return true
+				}
+			case *parse.ChainNode:
+				// A missing field of a chain is reported at the location
+				// of the node it continues
+				if p, ok := n.Node.(*parse.PipeNode); ok && f.pipe(p, scope) {
+					if !f.found.has(f.key) && slices.Contains(n.Field, f.key) {
+						f.found = foundNode{node: n, scope: scope}
+					}
+
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// withScope returns the scope within a "with" block checking p. It's only
+// known when p is a single field chain from a known scope.
+func withScope(p *parse.PipeNode, scope valueScope) valueScope {
+	unknown := valueScope{root: scope.root}
+	if len(p.Decl) > 0 || len(p.Cmds) != 1 || len(p.Cmds[0].Args) != 1 {
+		return unknown
+	}
+
+	f := &foundNode{node: p.Cmds[0].Args[0], scope: scope}
+	fields, base, known := f.fields()
+	if !known {
+		return unknown
+	}
+
+	return valueScope{path: append(append([]string{}, base...), fields...), known: true, root: scope.root}
+}
@@ -0,0 +1,133 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMissingValue(t *testing.T) {
+	values := map[string]any{
+		"name": "app",
+		"dbs":  1,
+		"database": map[string]any{
+			"port": 5432,
+			"host": "localhost",
+		},
+	}
+
+	tests := []struct {
+		name        string
+		template    string
+		helpers     map[string]string
+		path        string
+		suggestions []string
+	}{
+		{
+			name:        "typo in a nested key",
+			template:    `{{ .Values.database.prot }}`,
+			path:        ".Values.database.prot",
+			suggestions: []string{".Values.database.port"},
+		},
+		{
+			name:     "missing parent key",
+			template: `{{ .Values.db.port }}`,
+			path:     ".Values.db.port",
+		},
+		{
+			name:        "different case",
+			template:    `{{ .Name }}`,
+			path:        ".Name",
+			suggestions: []string{".name"},
+		},
+		{
+			name:        "within with",
+			template:    `{{ with .database }}{{ .hots }}{{ end }}`,
+			path:        ".database.hots",
+			suggestions: []string{".database.host"},
+		},
+		{
+			name:        "root variable within range",
+			template:    `{{ range list 1 2 }}{{ $.nmae }}{{ end }}`,
+			path:        "$.nmae",
+			suggestions: []string{"$.name"},
+		},
+		{
+			name:        "chain",
+			template:    `{{ (.database).prot }}`,
+			path:        ".database.prot",
+			suggestions: []string{".database.port"},
+		},
+		{
+			name:     "unknown dot within range",
+			template: `{{ range list .database }}{{ .prot }}{{ end }}`,
+			path:     ".prot",
+		},
+		{
+			name:     "unknown dot within helpers",
+			template: `{{ include "db" .database }}`,
+			helpers:  map[string]string{"_helpers.tpl": `{{ define "db" }}{{ .prot }}{{ end }}`},
+			path:     ".prot",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := []Option{WithTemplate("tpl", tt.template), WithValues(values), WithStrict(true)}
+			for name, content := range tt.helpers {
+				opts = append(opts, WithHelper(name, content))
+			}
+
+			err := New(opts...).Render(context.Background(), &bytes.Buffer{})
+
+			var terr *TemplateError
+			if !errors.As(err, &terr) {
+				t.Fatalf("render() error = %v, want a template error", err)
+			}
+
+			expected := missingValue{path: tt.path, suggestions: tt.suggestions}
+			if terr.Message != "missing value: "+expected.String() {
+				t.Errorf("Message = %q, want %q", terr.Message, "missing value: "+expected.String())
+			}
+
+			if !reflect.DeepEqual(terr.Suggestions, tt.suggestions) {
+				t.Errorf("Suggestions = %q, want %q", terr.Suggestions, tt.suggestions)
+			}
+
+			if err.Error() != "strict mode on: missing value in values file: "+expected.String() {
+				t.Errorf("Error() = %q", err.Error())
+			}
+
+			// Collecting errors reports the same problem
+			r := New(append(opts, WithCollectErrors(true))...)
+
+			var report *StrictReportError
+			if err := r.Render(context.Background(), &bytes.Buffer{}); !errors.As(err, &report) || len(report.Problems) != 1 || report.Problems[0].Message != terr.Message {
+				t.Errorf("render() collecting errors = %v, want %q", err, terr.Message)
+			}
+		})
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		distance int
+	}{
+		{"", "", 0},
+		{"port", "port", 0},
+		{"", "port", 4},
+		{"prot", "port", 1},
+		{"host", "hosts", 1},
+		{"kitten", "sitting", 3},
+		{"naïve", "naive", 1},
+	}
+
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.distance {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.distance)
+		}
+	}
+}
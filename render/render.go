@@ -0,0 +1,407 @@
+// Package render renders tgen templates: Go templates with tgen's and
+// Sprig's functions, where values are available both at the top level and
+// under ".Values", and environment variables can be read with "env".
+//
+// A Renderer is configured with options and can be rendered any number of
+// times:
+//
+//	r := render.New(
+//		render.WithTemplate("config.tpl", `port: {{ .Values.port }}`),
+//		render.WithValues(map[string]any{"port": 8080}),
+//		render.WithStrict(true),
+//	)
+//
+//	if err := r.Render(ctx, os.Stdout); err != nil {
+//		var terr *render.TemplateError
+//		if errors.As(err, &terr) {
+//			// terr.Kind, terr.Line, terr.Column...
+//		}
+//	}
+package render
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/patrickdappollonio/tgen/internal/maputil"
+	"github.com/patrickdappollonio/tgen/tfuncs"
+)
+
+// Renderer renders a template with its helpers, values and environment
+type Renderer struct {
+	name    string
+	content string
+
+	// helpers are parsed alongside the template so the templates they
+	// define can be used from it
+	helpers []helper
+
+	values map[string]any
+	env    tfuncs.Environment
+
+	leftDelim, rightDelim string
+
+	strict        bool
+	collectErrors bool
+
+	funcs      template.FuncMap
+	fsys       fs.FS
+	allowRead  []string
+	seed       *int64
+	store      func(key string, generate func() (string, error)) (string, error)
+	hermetic   *tfuncs.Hermetic
+	policy     *tfuncs.Policy
+	onFileRead func(path string)
+}
+
+// helper is a template parsed into the same set as the main one
+type helper struct {
+	name    string
+	content string
+}
+
+// Option configures a Renderer
+type Option func(*Renderer)
+
+// New returns a Renderer configured with opts, applied in order
+func New(opts ...Option) *Renderer {
+	r := &Renderer{}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// WithTemplate sets the template to render. The name is used in errors.
+func WithTemplate(name, content string) Option {
+	return func(r *Renderer) {
+		r.name = name
+		r.content = content
+	}
+}
+
+// WithHelper adds a helper template, parsed alongside the template so the
+// templates it defines can be used from it with "template" or "include"
+func WithHelper(name, content string) Option {
+	return func(r *Renderer) {
+		r.helpers = append(r.helpers, helper{name: name, content: content})
+	}
+}
+
+// WithValues deep-merges values on top of the ones set before, with the new
+// values taking precedence. Values are available both at the top level and
+// under ".Values".
+func WithValues(values map[string]any) Option {
+	return func(r *Renderer) {
+		r.values = MergeValues(r.values, values)
+	}
+}
+
+// WithEnv adds variables for the "env" and "envdefault" functions, replacing
+// the ones set before with the same name. Unless the environment is case
+// sensitive, names are uppercased.
+func WithEnv(values map[string]string) Option {
+	return func(r *Renderer) {
+		if r.env.Values == nil {
+			r.env.Values = make(map[string]string, len(values))
+		}
+
+		for k, v := range values {
+			r.env.Values[k] = v
+		}
+	}
+}
+
+// WithEnvironment sets how environment variables are looked up, replacing
+// the variables set before with the ones in env
+func WithEnvironment(env tfuncs.Environment) Option {
+	return func(r *Renderer) {
+		r.env = env
+	}
+}
+
+// WithDelimiters sets the action delimiters, "{{" and "}}" by default
+func WithDelimiters(left, right string) Option {
+	return func(r *Renderer) {
+		r.leftDelim, r.rightDelim = left, right
+	}
+}
+
+// WithStrict fails rendering when a value or environment variable used in
+// the template isn't set
+func WithStrict(strict bool) Option {
+	return func(r *Renderer) {
+		r.strict = strict
+	}
+}
+
+// WithCollectErrors keeps rendering in strict mode past missing values,
+// missing environment variables and failed required calls, reporting all
+// of them at once in a StrictReportError
+func WithCollectErrors(collect bool) Option {
+	return func(r *Renderer) {
+		r.collectErrors = collect
+	}
+}
+
+// WithFuncs adds functions to the template, replacing the built-in ones
+// with the same name
+func WithFuncs(funcs template.FuncMap) Option {
+	return func(r *Renderer) {
+		if r.funcs == nil {
+			r.funcs = make(template.FuncMap, len(funcs))
+		}
+
+		for k, v := range funcs {
+			r.funcs[k] = v
+		}
+	}
+}
+
+// WithFS makes the file and directory functions, like "readfile", read from
+// fsys instead of the host
+func WithFS(fsys fs.FS) Option {
+	return func(r *Renderer) {
+		r.fsys = fsys
+	}
+}
+
+// WithAllowRead confines the file and directory functions to the given
+// directories, see tfuncs.AllowReads. It has no effect with WithFS.
+func WithAllowRead(paths ...string) Option {
+	return func(r *Renderer) {
+		r.allowRead = append(r.allowRead, paths...)
+	}
+}
+
+// WithSeed makes every random function, like "rndstring", "uuidv4" or
+// "randInt", draw from a single source seeded with seed, so a template
+// calling them in the same order always gets the same results
+func WithSeed(seed int64) Option {
+	return func(r *Renderer) {
+		r.seed = &seed
+	}
+}
+
+// WithState makes "persistent" and "persistentwith" keep their values in
+// store, see tfuncs.Persist. Without a state, calling them fails.
+func WithState(store func(key string, generate func() (string, error)) (string, error)) Option {
+	return func(r *Renderer) {
+		r.store = store
+	}
+}
+
+// WithHermetic isolates rendering from the host, see tfuncs.Hermetic. The
+// environment is only read from the variables given with WithEnv.
+func WithHermetic(h tfuncs.Hermetic) Option {
+	return func(r *Renderer) {
+		r.hermetic = &h
+	}
+}
+
+// WithPolicy disables the functions denied by p. They still parse, but
+// fail when called.
+func WithPolicy(p tfuncs.Policy) Option {
+	return func(r *Renderer) {
+		r.policy = &p
+	}
+}
+
+// WithFileReadHook calls onRead with every path read by the file and
+// directory functions while rendering
+func WithFileReadHook(onRead func(path string)) Option {
+	return func(r *Renderer) {
+		r.onFileRead = onRead
+	}
+}
+
+// MergeValues deep-merges next on top of values, like WithValues, and
+// returns the result with its ".Values" alias. Neither map is modified.
+func MergeValues(values, next map[string]any) map[string]any {
+	merged := maputil.Merge(withoutAlias(values), withoutAlias(next))
+
+	// Create a copy for the Values key so both ".key" and ".Values.key" work
+	merged["Values"] = maputil.Copy(merged)
+	return merged
+}
+
+// withoutAlias returns the top level of values without its ".Values" alias
+func withoutAlias(values map[string]any) map[string]any {
+	m := make(map[string]any, len(values))
+	for k, v := range values {
+		if k != "Values" {
+			m[k] = v
+		}
+	}
+
+	return m
+}
+
+// Functions returns the functions available to templates, other than
+// "include": tgen's, and Sprig's ones not overridden by tgen
+func Functions(env tfuncs.Environment, strict bool) template.FuncMap {
+	return mergeFuncMaps(tfuncs.GetFunctions(env, strict), sprig.FuncMap())
+}
+
+func mergeFuncMaps(a, b template.FuncMap) template.FuncMap {
+	if a == nil {
+		a = template.FuncMap{}
+	}
+
+	for k, v := range b {
+		_, found := a[k]
+		if !found {
+			a[k] = v
+		}
+	}
+
+	return a
+}
+
+// environment returns the environment used by the env functions
+func (r *Renderer) environment() tfuncs.Environment {
+	env := r.env
+	env.FilesOnly = env.FilesOnly || r.hermetic != nil
+
+	env.Values = make(map[string]string, len(r.env.Values))
+	for k, v := range r.env.Values {
+		env.Values[env.Key(k)] = v
+	}
+
+	return env
+}
+
+// funcMap returns the functions for a render
+func (r *Renderer) funcMap(env tfuncs.Environment) template.FuncMap {
+	funcs := Functions(env, r.strict)
+	if r.fsys != nil {
+		funcs = tfuncs.ReadFromFS(funcs, r.fsys)
+	} else if len(r.allowRead) > 0 {
+		funcs = tfuncs.AllowReads(funcs, r.allowRead)
+	}
+
+	if r.seed != nil {
+		funcs = tfuncs.SeedRandom(funcs, *r.seed)
+	}
+
+	if r.store != nil {
+		funcs = tfuncs.Persist(funcs, r.store)
+	}
+
+	for k, v := range r.funcs {
+		funcs[k] = v
+	}
+
+	if r.onFileRead != nil {
+		funcs = tfuncs.TrackFileReads(funcs, r.onFileRead)
+	}
+
+	if r.hermetic != nil {
+		funcs = r.hermetic.Apply(funcs, env)
+	}
+
+	if r.collectErrors {
+		funcs = mergeFuncMaps(funcs, placeholderFuncs())
+	}
+
+	return funcs
+}
+
+// Render renders the template into w. Nothing is written when rendering
+// fails. Rendering stops with the context's error when ctx is done.
+func (r *Renderer) Render(ctx context.Context, w io.Writer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if r.collectErrors && !r.strict {
+		return errors.New("collecting errors requires strict mode")
+	}
+
+	funcs := r.funcMap(r.environment())
+
+	baseTemplate := template.New(r.name)
+	funcs["include"] = includeFunc(ctx, baseTemplate)
+
+	if r.policy != nil {
+		var err error
+		if funcs, err = r.policy.Apply(funcs); err != nil {
+			return err
+		}
+	}
+
+	baseTemplate = baseTemplate.Funcs(funcs)
+
+	if r.strict {
+		baseTemplate = baseTemplate.Option("missingkey=error")
+	} else {
+		baseTemplate = baseTemplate.Option("missingkey=zero")
+	}
+
+	if r.leftDelim != "" && r.rightDelim != "" {
+		baseTemplate = baseTemplate.Delims(r.leftDelim, r.rightDelim)
+	}
+
+	if err := r.parseHelpers(baseTemplate); err != nil {
+		return err
+	}
+
+	parsed, err := baseTemplate.Parse(r.content)
+	if err != nil {
+		return r.parseError(r.name, r.content, err)
+	}
+
+	var temp bytes.Buffer
+	out := &contextWriter{ctx: ctx, w: &temp}
+
+	if r.collectErrors {
+		if err := r.executeCollecting(parsed, out); err != nil {
+			return err
+		}
+	} else if err := parsed.Execute(out, r.values); err != nil {
+		return r.replaceTemplateRenderError(parsed, err)
+	}
+
+	if r.strict {
+		_, err = fmt.Fprint(w, temp.String())
+		return err
+	}
+
+	// Due to an unfortunate agreement and lack of behaviour change in the Go standard
+	// library, I'm forced to trim the <no value> string from the output directly.
+	// See helm's engine implementation of this
+	// https://github.com/helm/helm/blob/7ed9d16dc764a5b94b378a7e217865efaa0d9ac8/pkg/engine/engine.go#L267
+	// and the original issue, not solved but closed as wontfix:
+	// https://github.com/golang/go/issues/24963
+	str := strings.ReplaceAll(temp.String(), "<no value>", "")
+	_, err = fmt.Fprint(w, str)
+	return err
+}
+
+// contextWriter fails writes once ctx is done, which stops executing a
+// template at its next output
+type contextWriter struct {
+	ctx context.Context
+	w   *bytes.Buffer
+}
+
+func (c *contextWriter) Write(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return c.w.Write(p)
+}
+
+// reset discards everything written so far
+func (c *contextWriter) reset() {
+	c.w.Reset()
+}
@@ -0,0 +1,259 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"text/template"
+
+	"github.com/patrickdappollonio/tgen/tfuncs"
+)
+
+func TestRender(t *testing.T) {
+	t.Setenv("TGEN_RENDER_TEST_OS", "from-os")
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		expected string
+		wantErr  string
+	}{
+		{
+			name: "values at the top level and under .Values",
+			opts: []Option{
+				WithTemplate("tpl", `{{ .name }}={{ .Values.name }}`),
+				WithValues(map[string]any{"name": "app"}),
+			},
+			expected: "app=app",
+		},
+		{
+			name: "layered values",
+			opts: []Option{
+				WithTemplate("tpl", `{{ .db.host }}:{{ .db.port }}`),
+				WithValues(map[string]any{"db": map[string]any{"host": "localhost", "port": 5432}}),
+				WithValues(map[string]any{"db": map[string]any{"port": 6543}}),
+			},
+			expected: "localhost:6543",
+		},
+		{
+			name: "missing values without strict mode",
+			opts: []Option{
+				WithTemplate("tpl", `[{{ .missing }}]`),
+			},
+			expected: "[]",
+		},
+		{
+			name: "missing values with strict mode",
+			opts: []Option{
+				WithTemplate("tpl", `{{ .missing }}`),
+				WithStrict(true),
+			},
+			wantErr: "missing value in values file: .missing",
+		},
+		{
+			name: "env values",
+			opts: []Option{
+				WithTemplate("tpl", `{{ env "name" }} {{ env "TGEN_RENDER_TEST_OS" }}`),
+				WithEnv(map[string]string{"name": "from-env"}),
+			},
+			expected: "from-env from-os",
+		},
+		{
+			name: "case sensitive environment",
+			opts: []Option{
+				WithTemplate("tpl", `{{ env "name" }}|{{ env "NAME" }}`),
+				WithEnvironment(tfuncs.Environment{CaseSensitive: true}),
+				WithEnv(map[string]string{"name": "lower"}),
+			},
+			expected: "lower|",
+		},
+		{
+			name: "delimiters",
+			opts: []Option{
+				WithTemplate("tpl", `{{ kept }} [[ .name ]]`),
+				WithValues(map[string]any{"name": "app"}),
+				WithDelimiters("[[", "]]"),
+			},
+			expected: "{{ kept }} app",
+		},
+		{
+			name: "extra functions",
+			opts: []Option{
+				WithTemplate("tpl", `{{ greet .name }} {{ upper .name }}`),
+				WithValues(map[string]any{"name": "app"}),
+				WithFuncs(template.FuncMap{
+					"greet": func(s string) string { return "hello " + s },
+					"upper": func(s string) string { return "overridden" },
+				}),
+			},
+			expected: "hello app overridden",
+		},
+		{
+			name: "helpers",
+			opts: []Option{
+				WithTemplate("tpl", `{{ include "name" . | upper }}`),
+				WithHelper("_helpers.tpl", `{{ define "name" }}{{ .name }}{{ end }}`),
+				WithValues(map[string]any{"name": "app"}),
+			},
+			expected: "APP",
+		},
+		{
+			name: "file system",
+			opts: []Option{
+				WithTemplate("tpl", `{{ readfile "config/app.txt" }} {{ readdir "config" }}`),
+				WithFS(fstest.MapFS{"config/app.txt": {Data: []byte("from-fs")}}),
+			},
+			expected: "from-fs [app.txt]",
+		},
+		{
+			name: "hermetic ignores the OS environment",
+			opts: []Option{
+				WithTemplate("tpl", `{{ envdefault "TGEN_RENDER_TEST_OS" "unset" }}`),
+				WithHermetic(tfuncs.Hermetic{}),
+			},
+			expected: "unset",
+		},
+		{
+			name: "seeded random functions",
+			opts: []Option{
+				WithTemplate("tpl", `{{ randInt 0 1000000 }}`),
+				WithSeed(42),
+			},
+			expected: "72305",
+		},
+		{
+			name: "collecting errors requires strict mode",
+			opts: []Option{
+				WithTemplate("tpl", `{{ .missing }}`),
+				WithCollectErrors(true),
+			},
+			wantErr: "collecting errors requires strict mode",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := New(tt.opts...).Render(context.Background(), &buf)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Render() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+
+				if buf.Len() > 0 {
+					t.Errorf("Render() wrote %q on error", buf.String())
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Render() unexpected error: %v", err)
+			}
+
+			if buf.String() != tt.expected {
+				t.Errorf("Render() = %q, want %q", buf.String(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestRenderContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	r := New(WithTemplate("tpl", "hello"))
+	if err := r.Render(ctx, &bytes.Buffer{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Render() error = %v, want %v", err, context.Canceled)
+	}
+
+	// Canceling while rendering stops at the next output
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	r = New(
+		WithTemplate("tpl", `{{ range until 10 }}{{ cancel }}{{ . }}{{ end }}`),
+		WithFuncs(template.FuncMap{"cancel": func() string { cancel(); return "" }}),
+	)
+
+	var buf bytes.Buffer
+	if err := r.Render(ctx, &buf); !errors.Is(err, context.Canceled) {
+		t.Errorf("Render() error = %v, want %v", err, context.Canceled)
+	}
+
+	if buf.Len() > 0 {
+		t.Errorf("Render() wrote %q after being canceled", buf.String())
+	}
+}
+
+func TestRenderFileReadHook(t *testing.T) {
+	var reads []string
+	r := New(
+		WithTemplate("tpl", `{{ readfile "a.txt" }}{{ readlocaldir "." }}`),
+		WithFS(fstest.MapFS{"a.txt": {Data: []byte("a")}}),
+		WithFileReadHook(func(path string) { reads = append(reads, path) }),
+	)
+
+	if err := r.Render(context.Background(), &bytes.Buffer{}); err != nil {
+		t.Fatalf("Render() unexpected error: %v", err)
+	}
+
+	if expected := []string{"a.txt", "."}; !reflect.DeepEqual(reads, expected) {
+		t.Errorf("Render() read %q, want %q", reads, expected)
+	}
+}
+
+func TestMergeValues(t *testing.T) {
+	base := map[string]any{"db": map[string]any{"host": "localhost"}}
+	next := map[string]any{"db": map[string]any{"port": 5432}, "Values": "ignored"}
+
+	merged := MergeValues(base, next)
+
+	db := map[string]any{"host": "localhost", "port": 5432}
+	expected := map[string]any{"db": db, "Values": map[string]any{"db": db}}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Errorf("MergeValues() = %v, want %v", merged, expected)
+	}
+
+	if !reflect.DeepEqual(base, map[string]any{"db": map[string]any{"host": "localhost"}}) {
+		t.Errorf("MergeValues() modified its input: %v", base)
+	}
+
+	// Merging again keeps a single alias
+	again := MergeValues(merged, map[string]any{"name": "app"})
+	if _, nested := again["Values"].(map[string]any)["Values"]; nested {
+		t.Errorf("MergeValues() nested the alias: %v", again)
+	}
+}
+
+func TestRenderPolicy(t *testing.T) {
+	r := New(
+		WithTemplate("tpl", "ok\n{{ uuidv4 }}"),
+		WithPolicy(tfuncs.Policy{Sandbox: true}),
+	)
+
+	err := r.Render(context.Background(), &bytes.Buffer{})
+
+	var terr *TemplateError
+	if !errors.As(err, &terr) {
+		t.Fatalf("Render() error = %v, want a template error", err)
+	}
+
+	if terr.Kind != ErrorKindDisabled || terr.Line != 2 || terr.Column != 4 {
+		t.Errorf("Render() error = %s at %d:%d, want %s at 2:4", terr.Kind, terr.Line, terr.Column, ErrorKindDisabled)
+	}
+
+	if expected := `function "uuidv4" disabled by policy: it needs the "random" capability`; !strings.Contains(terr.Message, expected) {
+		t.Errorf("Message = %q, want it to contain %q", terr.Message, expected)
+	}
+
+	r = New(WithTemplate("tpl", "ok"), WithPolicy(tfuncs.Policy{DenyFunctions: []string{"nope"}}))
+	if err := r.Render(context.Background(), &bytes.Buffer{}); err == nil || !strings.Contains(err.Error(), `"nope"`) {
+		t.Errorf("Render() error = %v, want an unknown function error", err)
+	}
+}
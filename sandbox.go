@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/patrickdappollonio/tgen/tfuncs"
+)
+
+// policyConfig returns the function policy for the configuration, or nil
+// when no function is restricted
+func (c conf) policyConfig() (*tfuncs.Policy, error) {
+	if !c.sandbox && len(c.denyCapabilities) == 0 && len(c.denyFunctions) == 0 {
+		switch {
+		case len(c.allowCapabilities) > 0:
+			return nil, &missingArgError{"allow-capability", "sandbox"}
+		case len(c.allowFunctions) > 0:
+			return nil, &missingArgError{"allow-func", "sandbox"}
+		}
+
+		return nil, nil
+	}
+
+	if len(c.allowCapabilities) > 0 && !c.sandbox {
+		return nil, &missingArgError{"allow-capability", "sandbox"}
+	}
+
+	allow, err := parseCapabilities(c.allowCapabilities)
+	if err != nil {
+		return nil, err
+	}
+
+	deny, err := parseCapabilities(c.denyCapabilities)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tfuncs.Policy{
+		Sandbox:        c.sandbox,
+		Allow:          allow,
+		Deny:           deny,
+		AllowFunctions: splitList(c.allowFunctions),
+		DenyFunctions:  splitList(c.denyFunctions),
+	}, nil
+}
+
+// parseCapabilities parses the capabilities given to a flag
+func parseCapabilities(values []string) ([]tfuncs.Capability, error) {
+	var capabilities []tfuncs.Capability
+	for _, name := range splitList(values) {
+		c, err := tfuncs.ParseCapability(name)
+		if err != nil {
+			return nil, err
+		}
+
+		capabilities = append(capabilities, c)
+	}
+
+	return capabilities, nil
+}
+
+// splitList returns every item of a repeatable flag, where each value can
+// also be a comma-separated list
+func splitList(values []string) []string {
+	var items []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+
+	return items
+}
+
+// allowReadRoots returns the absolute paths of the directories given to
+// --allow-read, which must exist
+func (c conf) allowReadRoots() ([]string, error) {
+	roots := make([]string, 0, len(c.allowRead))
+	for _, path := range c.allowRead {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to allow reads from %q: %w", path, err)
+		}
+
+		info, err := os.Stat(abs)
+		if err != nil {
+			return nil, fmt.Errorf("unable to allow reads from %q: %w", path, err)
+		}
+
+		if !info.IsDir() {
+			return nil, fmt.Errorf("unable to allow reads from %q: path is not a directory", path)
+		}
+
+		roots = append(roots, abs)
+	}
+
+	return roots, nil
+}
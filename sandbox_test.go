@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/patrickdappollonio/tgen/tfuncs"
+)
+
+func TestSandboxCommand(t *testing.T) {
+	t.Setenv("TGEN_TEST_SANDBOX", "from-os")
+
+	dir := t.TempDir()
+	tpl := filepath.Join(dir, "template.tpl")
+	writeTestFile(t, tpl, `{{ .name | upper }} {{ env "TGEN_TEST_SANDBOX" }}`, 0o644)
+
+	render := func(args ...string) (string, error) {
+		configs, _ := parseRenderFlags(t, args...)
+
+		var buf bytes.Buffer
+		err := command(&buf, *configs)
+		return buf.String(), err
+	}
+
+	if _, err := render("--sandbox", "-f", tpl, "--set", "name=app"); err == nil || !strings.Contains(err.Error(), `function "env" disabled by policy: it needs the "env" capability`) {
+		t.Errorf("command() error = %v, want a disabled function error", err)
+	}
+
+	for _, args := range [][]string{
+		{"--sandbox", "--allow-capability", "env"},
+		{"--sandbox", "--allow-capability", "time,env"},
+		{"--sandbox", "--allow-func", "env"},
+		{"--deny-capability", "fs"},
+	} {
+		out, err := render(append(args, "-f", tpl, "--set", "name=app")...)
+		if err != nil {
+			t.Fatalf("command(%q) unexpected error: %v", args, err)
+		}
+
+		if expected := "APP from-os"; out != expected {
+			t.Errorf("command(%q) = %q, want %q", args, out, expected)
+		}
+	}
+
+	if _, err := render("--deny-func", "upper", "-f", tpl, "--set", "name=app"); err == nil || !strings.Contains(err.Error(), `function "upper" disabled by policy`) {
+		t.Errorf("command() error = %v, want a disabled function error", err)
+	}
+
+	// The state file is only reachable with the "fs" capability
+	state := filepath.Join(dir, "state.json")
+	persistent := filepath.Join(dir, "persistent.tpl")
+	writeTestFile(t, persistent, `{{ persistent "token" "secret" }}`, 0o644)
+
+	if _, err := render("--sandbox", "--state", state, "-f", persistent); err == nil || !strings.Contains(err.Error(), `function "persistent" disabled by policy: it needs the "fs" capability`) {
+		t.Errorf("command() error = %v, want a disabled function error", err)
+	}
+
+	if _, err := os.Stat(state); !os.IsNotExist(err) {
+		t.Errorf("a sandboxed render created the state file: %v", err)
+	}
+
+	if out, err := render("--sandbox", "--allow-capability", "fs", "--state", state, "-f", persistent); err != nil || out != "secret" {
+		t.Errorf("command() = %q, %v, want %q", out, err, "secret")
+	}
+
+	var missing *missingArgError
+	if _, err := render("--allow-capability", "env", "-f", tpl); !errors.As(err, &missing) {
+		t.Errorf("command() error = %v, want a missing --sandbox error", err)
+	}
+}
+
+func TestPolicyConfig(t *testing.T) {
+	configs, _ := parseRenderFlags(t, "--sandbox", "--allow-capability", "fs", "--allow-capability", "random, time", "--deny-func", "uuidv4")
+
+	policy, err := configs.policyConfig()
+	if err != nil {
+		t.Fatalf("policyConfig() unexpected error: %v", err)
+	}
+
+	if !policy.Sandbox || len(policy.Allow) != 3 || policy.Allow[2] != tfuncs.CapabilityTime || len(policy.DenyFunctions) != 1 {
+		t.Errorf("policyConfig() = %+v", policy)
+	}
+
+	configs, _ = parseRenderFlags(t)
+	if policy, err := configs.policyConfig(); policy != nil || err != nil {
+		t.Errorf("policyConfig() = %+v, %v, want no policy", policy, err)
+	}
+
+	configs, _ = parseRenderFlags(t, "--deny-capability", "disk")
+	if _, err := configs.policyConfig(); err == nil || !strings.Contains(err.Error(), `unknown capability "disk"`) {
+		t.Errorf("policyConfig() error = %v, want an unknown capability error", err)
+	}
+}
+
+func TestAllowReadCommand(t *testing.T) {
+	dir := t.TempDir()
+	shared := filepath.Join(dir, "shared")
+	writeTestFile(t, filepath.Join(shared, "common.txt"), "common", 0o644)
+	writeTestFile(t, filepath.Join(dir, "shared-other", "secret.txt"), "secret", 0o644)
+
+	render := func(args ...string) (string, error) {
+		configs, _ := parseRenderFlags(t, args...)
+
+		var buf bytes.Buffer
+		err := command(&buf, *configs)
+		return buf.String(), err
+	}
+
+	out, err := render("--allow-read", shared, "-x", `{{ readfile "`+filepath.Join(shared, "common.txt")+`" }}`)
+	if err != nil {
+		t.Fatalf("command() unexpected error: %v", err)
+	}
+
+	if out != "common" {
+		t.Errorf("command() = %q, want %q", out, "common")
+	}
+
+	if _, err := render("--allow-read", shared, "-x", `{{ readfile "`+filepath.Join(dir, "shared-other", "secret.txt")+`" }}`); err == nil || !strings.Contains(err.Error(), "outside of the allowed directories") {
+		t.Errorf("command() error = %v, want an error reading outside of the allowed directories", err)
+	}
+
+	if _, err := render("--allow-read", filepath.Join(shared, "common.txt"), "-x", "ok"); err == nil || !strings.Contains(err.Error(), "not a directory") {
+		t.Errorf("command() error = %v, want a not a directory error", err)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/patrickdappollonio/tgen/internal/jsonschema"
+	"github.com/patrickdappollonio/tgen/internal/maputil"
+	"github.com/patrickdappollonio/tgen/tfuncs"
+)
+
+// defaultSchemaFile is the name of the schema file automatically detected
+// next to the first values file, like Helm charts do
+const defaultSchemaFile = "values.schema.json"
+
+// schemaPath returns the JSON schema to validate values against, which is
+// either the one given with --schema, or a "values.schema.json" file found
+// in the same directory as the first values file
+func (c conf) schemaPath() string {
+	if c.schemaFile != "" {
+		return c.schemaFile
+	}
+
+	files := c.allValuesFiles()
+	if c.skipSchemaValidation || len(files) == 0 {
+		return ""
+	}
+
+	candidate := filepath.Join(filepath.Dir(files[0]), defaultSchemaFile)
+	if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+		return candidate
+	}
+
+	return ""
+}
+
+// validateValues validates the merged values against the JSON schema at
+// schemapath. Defaults declared in the schema are applied to the values
+// before validating them.
+func (t *tgen) validateValues(schemapath string) error {
+	bf, err := tfuncs.ReadFile(schemapath)
+	if err != nil {
+		return err
+	}
+
+	schema, err := jsonschema.Parse([]byte(bf))
+	if err != nil {
+		return &schemaError{path: schemapath, original: err}
+	}
+
+	// Validate the values without the ".Values" alias, which is
+	// rebuilt once defaults are applied
+	values := make(map[string]any)
+	for k, v := range t.yamlValues {
+		if k != "Values" {
+			values[k] = v
+		}
+	}
+
+	values = maputil.Copy(values)
+
+	if err := schema.ApplyDefaults(values); err != nil {
+		return &schemaError{path: schemapath, original: err}
+	}
+
+	if err := schema.Validate(values); err != nil {
+		return &schemaError{path: schemapath, original: err}
+	}
+
+	t.yamlValues = nil
+	t.mergeValues(values)
+	return nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/patrickdappollonio/tgen/internal/jsonschema"
+	"github.com/patrickdappollonio/tgen/internal/setflags"
+)
+
+const testSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object",
+	"required": ["name"],
+	"properties": {
+		"name": {"type": "string"},
+		"replicas": {"type": "integer", "minimum": 1, "default": 2},
+		"db": {
+			"type": "object",
+			"properties": {
+				"port": {"type": "integer"}
+			}
+		}
+	}
+}`
+
+func TestCommandSchemaValidation(t *testing.T) {
+	dir := t.TempDir()
+	values := filepath.Join(dir, "values.yaml")
+	writeTestFile(t, values, "name: app\n", 0o644)
+	writeTestFile(t, filepath.Join(dir, defaultSchemaFile), testSchema, 0o644)
+
+	t.Run("defaults from auto-detected schema", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := command(&buf, conf{stdinTemplateFile: "{{ .name }}={{ .Values.replicas }}", valuesFiles: []string{values}})
+		if err != nil {
+			t.Fatalf("command() unexpected error: %v", err)
+		}
+
+		if buf.String() != "app=2" {
+			t.Errorf("command() = %q, want %q", buf.String(), "app=2")
+		}
+	})
+
+	t.Run("set flags are validated", func(t *testing.T) {
+		err := command(nil, conf{
+			stdinTemplateFile: "{{ .name }}",
+			valuesFiles:       []string{values},
+			setFlags:          []setflags.Flag{{Kind: setflags.KindSet, Value: "replicas=0"}, {Kind: setflags.KindSet, Value: "db.port=http"}},
+		})
+
+		var verr *jsonschema.ValidationError
+		if !errors.As(err, &verr) {
+			t.Fatalf("command() error = %v, want *jsonschema.ValidationError", err)
+		}
+
+		if len(verr.Violations) != 2 {
+			t.Errorf("command() violations = %v, want 2", verr.Violations)
+		}
+
+		for _, want := range []string{"/db/port: expected integer, got string", "/replicas: value must be greater than or equal to 1"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("command() error %q doesn't mention %q", err.Error(), want)
+			}
+		}
+	})
+
+	t.Run("skip auto-detected schema", func(t *testing.T) {
+		err := command(&bytes.Buffer{}, conf{
+			stdinTemplateFile:    "{{ .name }}",
+			valuesFiles:          []string{values},
+			setFlags:             []setflags.Flag{{Kind: setflags.KindSet, Value: "replicas=0"}},
+			skipSchemaValidation: true,
+		})
+		if err != nil {
+			t.Errorf("command() unexpected error: %v", err)
+		}
+	})
+
+	t.Run("explicit schema without values files", func(t *testing.T) {
+		err := command(nil, conf{
+			stdinTemplateFile: "{{ .name }}",
+			schemaFile:        filepath.Join(dir, defaultSchemaFile),
+		})
+		if err == nil || !strings.Contains(err.Error(), `missing required property "name"`) {
+			t.Errorf("command() error = %v, want missing required property", err)
+		}
+	})
+}
+
+func TestCommandSchemaTOMLDates(t *testing.T) {
+	dir := t.TempDir()
+	values := filepath.Join(dir, "values.toml")
+	schema := filepath.Join(dir, "schema.json")
+	writeTestFile(t, values, "released = 2024-05-01T10:30:00Z\nday = 2024-05-01\n", 0o644)
+	writeTestFile(t, schema, `{
+	"type": "object",
+	"properties": {
+		"released": {"type": "string", "format": "date-time", "pattern": "^2024-05-01T10:30:00"},
+		"day": {"type": "string"}
+	}
+}`, 0o644)
+
+	var buf bytes.Buffer
+	err := command(&buf, conf{
+		stdinTemplateFile: "{{ .released.Year }}",
+		valuesFiles:       []string{values},
+		schemaFile:        schema,
+	})
+	if err != nil {
+		t.Fatalf("command() unexpected error: %v", err)
+	}
+
+	if buf.String() != "2024" {
+		t.Errorf("command() = %q, want %q", buf.String(), "2024")
+	}
+
+	writeTestFile(t, schema, `{"properties": {"day": {"type": "integer"}}}`, 0o644)
+	err = command(nil, conf{stdinTemplateFile: "{{ .day }}", valuesFiles: []string{values}, schemaFile: schema})
+	if err == nil || !strings.Contains(err.Error(), "/day: expected integer, got string") {
+		t.Errorf("command() error = %v, want dates to be strings", err)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// seedFlagValue is the flag for the random functions' seed. It's a pointer
+// so "--seed 0" can be told apart from no seed at all.
+type seedFlagValue struct {
+	seed **int64
+}
+
+func (s *seedFlagValue) Set(value string) error {
+	n, err := strconv.ParseInt(value, 0, 64)
+	if err != nil {
+		return fmt.Errorf("invalid seed %q: must be an integer", value)
+	}
+
+	*s.seed = &n
+	return nil
+}
+
+func (s *seedFlagValue) Type() string {
+	return "int"
+}
+
+func (s *seedFlagValue) String() string {
+	// An empty string keeps the help output from showing a default
+	if s.seed == nil || *s.seed == nil {
+		return ""
+	}
+
+	return strconv.FormatInt(**s.seed, 10)
+}
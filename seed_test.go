@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSeedCommand(t *testing.T) {
+	tpl := `{{ rndstring 8 }} {{ rnditem (list 1 2 3) }} {{ uuidv4 }} {{ randAlphaNum 8 }} {{ shuffle "abcdefgh" }} {{ randInt 1 1000 }}`
+
+	render := func(args ...string) string {
+		configs, _ := parseRenderFlags(t, append(args, "-x", tpl)...)
+
+		var buf bytes.Buffer
+		if err := command(&buf, *configs); err != nil {
+			t.Fatalf("command() unexpected error: %v", err)
+		}
+
+		return buf.String()
+	}
+
+	if first, second := render("--seed", "42"), render("--seed", "42"); first != second {
+		t.Errorf("renders with the same seed differ: %q and %q", first, second)
+	}
+
+	if first, second := render("--seed", "0"), render("--seed", "0"); first != second {
+		t.Errorf("renders with a zero seed differ: %q and %q", first, second)
+	}
+
+	if first, second := render("--seed", "1"), render("--seed", "2"); first == second {
+		t.Errorf("renders with different seeds are both %q", first)
+	}
+
+	if first, second := render(), render(); first == second {
+		t.Errorf("renders without a seed are both %q", first)
+	}
+}
+
+func TestSeedFlag(t *testing.T) {
+	configs, flags := parseRenderFlags(t)
+	if configs.seed != nil {
+		t.Errorf("seed = %d, want no seed", *configs.seed)
+	}
+
+	if err := flags.Set("seed", "0x10"); err != nil {
+		t.Fatalf("unable to set --seed: %v", err)
+	}
+
+	if configs.seed == nil || *configs.seed != 16 {
+		t.Errorf("seed = %v, want 16", configs.seed)
+	}
+
+	if err := flags.Set("seed", "nope"); err == nil {
+		t.Error("expected an error setting an invalid seed")
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/patrickdappollonio/tgen/internal/setflags"
+)
+
+// setFlagValue is a repeatable flag that appends its values, tagged with the
+// flag's kind, to a list shared by every set flag, so they can be applied in
+// the order they were given on the command line
+type setFlagValue struct {
+	kind  string
+	flags *[]setflags.Flag
+}
+
+func (s *setFlagValue) Set(value string) error {
+	*s.flags = append(*s.flags, setflags.Flag{Kind: s.kind, Value: value})
+	return nil
+}
+
+// Type returns the same type as pflag's string arrays, since this flag can
+// be repeated like one
+func (s *setFlagValue) Type() string {
+	return "stringArray"
+}
+
+func (s *setFlagValue) String() string {
+	var values []string
+	if s.flags != nil {
+		for _, f := range *s.flags {
+			if f.Kind == s.kind {
+				values = append(values, strconv.Quote(f.Value))
+			}
+		}
+	}
+
+	// An empty string keeps the help output from showing a default
+	if len(values) == 0 {
+		return ""
+	}
+
+	return "[" + strings.Join(values, ",") + "]"
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetFlagsCommandLineOrder(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "motd.txt")
+	writeTestFile(t, file, "from a file", 0o644)
+
+	configs, _ := parseRenderFlags(t,
+		"-x", "{{ .a }} {{ .b }} {{ .c.d }} {{ .e }}",
+		"--set-json", `a=1,c={"d": "json"}`,
+		"--set", "a=2",
+		"--set-file", "b="+file,
+		"--set-string", "e=003",
+		"--set", "e=4",
+		"--set-string", "c.d=last",
+	)
+
+	var buf bytes.Buffer
+	if err := command(&buf, *configs); err != nil {
+		t.Fatalf("command() unexpected error: %v", err)
+	}
+
+	if expected := "2 from a file last 4"; buf.String() != expected {
+		t.Errorf("command() = %q, want %q", buf.String(), expected)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultSplitName is the template used to name the files written by
+// --split-output, which fits Kubernetes manifests
+const defaultSplitName = "{{ .kind | lower }}-{{ .metadata.name }}.yaml"
+
+// splitDocuments parses rendered as a stream of YAML documents and writes
+// each non-empty document to its own file within dir. File names are the
+// result of rendering nameTemplate with the document as its values. Every
+// document is named before anything is written, so an invalid or colliding
+// name leaves dir untouched.
+func (t *tgen) splitDocuments(rendered []byte, dir, nameTemplate string) error {
+	if nameTemplate == "" {
+		nameTemplate = defaultSplitName
+	}
+
+	var names []string
+	files := make(map[string][]byte)
+	written := make(map[string]int)
+
+	for i, contents := range yamlDocuments(rendered) {
+		index := i + 1
+
+		var doc yaml.Node
+		if err := yaml.Unmarshal(contents, &doc); err != nil {
+			return fmt.Errorf("unable to split rendered output: document %d: %w", index, err)
+		}
+
+		if isEmptyDocument(&doc) {
+			continue
+		}
+
+		name, err := t.documentName(&doc, nameTemplate)
+		if err != nil {
+			return fmt.Errorf("unable to name document %d: %w", index, err)
+		}
+
+		if previous, found := written[name]; found {
+			return fmt.Errorf("documents %d and %d would both be written to %q", previous, index, name)
+		}
+
+		written[name] = index
+		files[name] = contents
+		names = append(names, name)
+	}
+
+	for _, name := range names {
+		dst := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return err
+		}
+
+		if err := writeFileIfChanged(dst, files[name], 0); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// yamlDocuments splits a stream of YAML documents on its "---" and "..."
+// markers, keeping each document as written, comments and indentation
+// included. Markers are only recognized at the start of a line, like YAML
+// does, and content following a "---" on the same line is kept.
+func yamlDocuments(stream []byte) [][]byte {
+	var (
+		docs    [][]byte
+		current []byte
+	)
+
+	flush := func() {
+		docs = append(docs, finishDocument(current))
+		current = nil
+	}
+
+	for _, line := range bytes.SplitAfter(stream, []byte("\n")) {
+		trimmed := bytes.TrimRight(line, "\r\n")
+
+		switch {
+		case isMarker(trimmed, "---"):
+			flush()
+			if rest := bytes.TrimSpace(trimmed[3:]); len(rest) > 0 {
+				current = append(current, rest...)
+				current = append(current, '\n')
+			}
+
+		case isMarker(trimmed, "..."):
+			flush()
+
+		default:
+			current = append(current, line...)
+		}
+	}
+
+	return append(docs, finishDocument(current))
+}
+
+// isMarker reports whether line is the document marker, alone or followed
+// by whitespace
+func isMarker(line []byte, marker string) bool {
+	if !bytes.HasPrefix(line, []byte(marker)) {
+		return false
+	}
+
+	rest := line[len(marker):]
+	return len(rest) == 0 || rest[0] == ' ' || rest[0] == '\t'
+}
+
+// finishDocument drops the blank lines around a document and makes sure it
+// ends with a newline
+func finishDocument(doc []byte) []byte {
+	for len(doc) > 0 {
+		end := bytes.IndexByte(doc, '\n')
+		if end < 0 || len(bytes.TrimSpace(doc[:end])) > 0 {
+			break
+		}
+
+		doc = doc[end+1:]
+	}
+
+	doc = bytes.TrimRightFunc(doc, unicode.IsSpace)
+	if len(doc) == 0 {
+		return nil
+	}
+
+	return append(doc, '\n')
+}
+
+// isEmptyDocument reports whether a YAML document has no content, like the
+// ones between two consecutive "---" separators or made only of comments
+func isEmptyDocument(doc *yaml.Node) bool {
+	if len(doc.Content) == 0 {
+		return true
+	}
+
+	root := doc.Content[0]
+	return root.Kind == yaml.ScalarNode && root.Tag == "!!null"
+}
+
+// documentName renders nameTemplate with the document as its values and
+// checks the result is a usable relative file name
+func (t *tgen) documentName(doc *yaml.Node, nameTemplate string) (string, error) {
+	var values map[string]any
+	if err := doc.Decode(&values); err != nil {
+		return "", errors.New("only mappings can be split into separate files")
+	}
+
+	tg := t.helper("split-name", nameTemplate)
+	tg.helpers = nil
+	tg.preDelimiter, tg.postDelimiter = "", ""
+	tg.yamlValues = values
+
+	var buf bytes.Buffer
+	if err := tg.render(&buf); err != nil {
+		return "", err
+	}
+
+	name := strings.TrimSpace(buf.String())
+	if name == "" {
+		return "", errors.New("name template rendered an empty file name")
+	}
+
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("file name %q must be relative and within the split output directory", name)
+	}
+
+	// "a.yaml", "./a.yaml" and "dir/../a.yaml" are the same file, so they
+	// must collide
+	return filepath.Clean(name), nil
+}
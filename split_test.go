@@ -0,0 +1,165 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/patrickdappollonio/tgen/internal/setflags"
+)
+
+func TestSplitDocuments(t *testing.T) {
+	rendered := `---
+# the first service
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+---
+---
+# only a comment
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 2 # scaled by hand
+  template:
+    data: |
+      ---
+      not a separator
+...
+`
+
+	dir := t.TempDir()
+	tg := &tgen{}
+	if err := tg.splitDocuments([]byte(rendered), dir, ""); err != nil {
+		t.Fatalf("splitDocuments() unexpected error: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("unable to read split directory: %v", err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("splitDocuments() wrote %d files, want 2", len(entries))
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{
+			name: "service-web.yaml",
+			want: "# the first service\napiVersion: v1\nkind: Service\nmetadata:\n  name: web\n",
+		},
+		{
+			name: "deployment-web.yaml",
+			want: "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\nspec:\n  replicas: 2 # scaled by hand\n  template:\n    data: |\n      ---\n      not a separator\n",
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := os.ReadFile(filepath.Join(dir, tt.name))
+		if err != nil {
+			t.Fatalf("unable to read split file: %v", err)
+		}
+
+		if string(got) != tt.want {
+			t.Errorf("%s = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSplitDocumentsErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		rendered string
+		template string
+		want     string
+	}{
+		{
+			name:     "name collision",
+			rendered: "kind: Service\nmetadata:\n  name: web\n---\nkind: Service\nmetadata:\n  name: web\n",
+			want:     `documents 1 and 2 would both be written to "service-web.yaml"`,
+		},
+		{
+			name:     "equivalent names",
+			rendered: "name: a.yaml\n---\nname: ./a.yaml\n---\nname: dir/../a.yaml\n",
+			template: "{{ .name }}",
+			want:     `documents 1 and 2 would both be written to "a.yaml"`,
+		},
+		{
+			name:     "escaping cleaned name",
+			rendered: "name: a\n",
+			template: "dir/../../{{ .name }}.yaml",
+			want:     "must be relative",
+		},
+		{
+			name:     "not a mapping",
+			rendered: "- a\n- b\n",
+			want:     "only mappings can be split",
+		},
+		{
+			name:     "escaping name",
+			rendered: "name: a\n",
+			template: "../{{ .name }}.yaml",
+			want:     "must be relative",
+		},
+		{
+			name:     "empty name",
+			rendered: "name: a\n",
+			template: "{{ .missing }}",
+			want:     "empty file name",
+		},
+		{
+			name:     "invalid yaml",
+			rendered: "a: b\n---\n: : :\n",
+			template: "{{ .a }}.yaml",
+			want:     "document 2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tg := &tgen{}
+			err := tg.splitDocuments([]byte(tt.rendered), t.TempDir(), tt.template)
+			if err == nil {
+				t.Fatal("splitDocuments() expected error but got none")
+			}
+
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("splitDocuments() error = %q, want it to contain %q", err.Error(), tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandSplitOutput(t *testing.T) {
+	dir := t.TempDir()
+
+	c := conf{
+		stdinTemplateFile: "{{ range .names }}---\nkind: ConfigMap\nmetadata:\n  name: {{ . }}\n{{ end }}",
+		setFlags:          []setflags.Flag{{Kind: setflags.KindSet, Value: "names={a,b}"}},
+		splitOutput:       dir,
+		splitName:         "{{ .metadata.name }}.yaml",
+	}
+
+	if err := command(os.Stdout, c); err != nil {
+		t.Fatalf("command() unexpected error: %v", err)
+	}
+
+	for _, name := range []string{"a.yaml", "b.yaml"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("expected %s to be written: %v", name, err)
+		}
+	}
+
+	c.outputFile = filepath.Join(dir, "out.yaml")
+	if err := command(os.Stdout, c); err == nil || !strings.Contains(err.Error(), "split-output") {
+		t.Errorf("command() error = %v, want a conflict with --output", err)
+	}
+}
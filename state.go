@@ -0,0 +1,388 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// stateFileVersion is the version of the state file format written by tgen
+const stateFileVersion = 1
+
+// stateFileMode is the mode of new state files, which usually hold secrets
+const stateFileMode fs.FileMode = 0o600
+
+// Status of state entries, as shown by "tgen state list"
+const (
+	stateUsed   = "used"
+	stateUnused = "unused"
+)
+
+// state keeps the values stored by "persistent" across renders in a JSON
+// file, so a value generated on the first render is reused on later ones.
+// A single state file can be shared by several templates, or by the jobs
+// of a project, so entries keep track of the templates using them.
+type state struct {
+	path   string
+	exists bool
+	data   stateFileData
+
+	// used are the keys requested while rendering each template, and
+	// rendered the templates whose render finished successfully
+	used     map[string]map[string]bool
+	rendered map[string]bool
+}
+
+// stateFileData is the contents of a state file
+type stateFileData struct {
+	Version int                    `json:"version"`
+	Entries map[string]*stateEntry `json:"entries"`
+
+	// Templates are every template that stored or read a value in the file
+	Templates []string `json:"templates,omitempty"`
+}
+
+// stateEntry is a value stored under a key
+type stateEntry struct {
+	Value   string    `json:"value"`
+	Created time.Time `json:"created"`
+
+	// Templates are the templates that used the entry on their last
+	// successful render, or since then
+	Templates []string `json:"templates,omitempty"`
+
+	// Unused marks entries that none of their templates used on their
+	// last successful render, which "tgen state prune" removes
+	Unused bool `json:"unused,omitempty"`
+}
+
+// loadState reads the state file at path. A file that doesn't exist yet is
+// an empty state, created when saved.
+func loadState(path string) (*state, error) {
+	s := &state{
+		path:     path,
+		data:     stateFileData{Version: stateFileVersion, Entries: make(map[string]*stateEntry)},
+		used:     make(map[string]map[string]bool),
+		rendered: make(map[string]bool),
+	}
+
+	contents, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to read state file %q: %w", path, err)
+	}
+
+	if err := json.Unmarshal(contents, &s.data); err != nil {
+		return nil, fmt.Errorf("unable to parse state file %q: %w", path, err)
+	}
+
+	if s.data.Version > stateFileVersion {
+		return nil, fmt.Errorf("unable to read state file %q: version %d is newer than the supported version %d", path, s.data.Version, stateFileVersion)
+	}
+
+	if s.data.Entries == nil {
+		s.data.Entries = make(map[string]*stateEntry)
+	}
+
+	s.data.Version = stateFileVersion
+	s.exists = true
+	return s, nil
+}
+
+// persistent returns the function backing "persistent" while rendering
+// the named template. It returns the value stored under key, storing a
+// generated value first when there's none.
+func (s *state) persistent(template string) func(key string, generate func() (string, error)) (string, error) {
+	return func(key string, generate func() (string, error)) (string, error) {
+		entry, found := s.data.Entries[key]
+		if !found {
+			value, err := generate()
+			if err != nil {
+				return "", err
+			}
+
+			entry = &stateEntry{Value: value, Created: time.Now().UTC().Truncate(time.Second)}
+			s.data.Entries[key] = entry
+		}
+
+		if s.used[template] == nil {
+			s.used[template] = make(map[string]bool)
+		}
+
+		s.used[template][key] = true
+
+		entry.Templates = addTemplate(entry.Templates, template)
+		s.data.Templates = addTemplate(s.data.Templates, template)
+
+		entry.Unused = false
+		return entry.Value, nil
+	}
+}
+
+// stateTemplate returns the name the entries used by t are recorded under:
+// the template, or the one owning a helper expression, and the project job
+// rendering it, if any, since several jobs can render the same template
+// with different keys
+func (t *tgen) stateTemplate() string {
+	name := t.templateFileName
+	if t.owner != "" {
+		name = t.owner
+	}
+
+	if t.job == "" {
+		return name
+	}
+
+	return fmt.Sprintf("%s (job %q)", name, t.job)
+}
+
+// helper returns a copy of t that renders the helper expression content,
+// like a front matter field, on behalf of t's template
+func (t *tgen) helper(name, content string) tgen {
+	tg := *t
+	if tg.owner == "" {
+		tg.owner = t.templateFileName
+	}
+
+	tg.setTemplate(name, content)
+	return tg
+}
+
+// addTemplate adds template to the sorted templates, unless it's already there
+func addTemplate(templates []string, template string) []string {
+	if idx, found := slices.BinarySearch(templates, template); !found {
+		templates = slices.Insert(templates, idx, template)
+	}
+
+	return templates
+}
+
+// renderedTemplate records that the named template rendered successfully,
+// so the entries it didn't use are no longer counted as its own. A failed
+// render can stop before using every key, so it's never recorded.
+func (s *state) renderedTemplate(template string) {
+	s.rendered[template] = true
+}
+
+// save writes the state file atomically, only when its contents changed.
+// Entries are only marked as unused once every template that used them
+// rendered successfully without them; entries of templates that weren't
+// rendered, or were skipped, are left as they were.
+func (s *state) save() error {
+	for key, entry := range s.data.Entries {
+		templates := slices.DeleteFunc(slices.Clone(entry.Templates), func(template string) bool {
+			return s.rendered[template] && !s.used[template][key]
+		})
+
+		if len(templates) != len(entry.Templates) {
+			entry.Templates = templates
+			entry.Unused = len(templates) == 0
+		}
+	}
+
+	// Don't create a state file nothing was stored in
+	if !s.exists && len(s.data.Entries) == 0 {
+		return nil
+	}
+
+	contents, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to encode state file %q: %w", s.path, err)
+	}
+
+	var mode fs.FileMode
+	if !s.exists {
+		mode = stateFileMode
+	}
+
+	if err := writeFileIfChanged(s.path, append(contents, '\n'), mode); err != nil {
+		return fmt.Errorf("unable to write state file %q: %w", s.path, err)
+	}
+
+	s.exists = true
+	return nil
+}
+
+// keys returns the keys of every entry, sorted
+func (s *state) keys() []string {
+	keys := make([]string, 0, len(s.data.Entries))
+	for key := range s.data.Entries {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+	return keys
+}
+
+func newStateCommand() *cobra.Command {
+	var path string
+
+	stateCmd := &cobra.Command{
+		Use:   "state",
+		Short: "manage the values stored by \"persistent\" in a state file",
+		Long: "Manage the values stored by the \"persistent\" template function in the file given to --state.\n" +
+			"Values are generated on the first render that uses their key and reused on every later one.",
+	}
+
+	stateCmd.PersistentFlags().StringVar(&path, "state", "", "the state file to manage")
+
+	// load applies the project configuration, which can set the state file
+	load := func(cmd *cobra.Command) (*state, error) {
+		if err := applyProjectConfig(cmd.Flags(), ""); err != nil {
+			return nil, err
+		}
+
+		if path == "" {
+			return nil, errors.New("no state file to manage: use --state")
+		}
+
+		if _, err := os.Stat(path); err != nil {
+			return nil, fmt.Errorf("unable to read state file %q: %w", path, err)
+		}
+
+		return loadState(path)
+	}
+
+	var showValues bool
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "list the entries in the state file",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := load(cmd)
+			if err != nil {
+				return err
+			}
+
+			return s.list(cmd.OutOrStdout(), showValues)
+		},
+	}
+
+	list.Flags().BoolVar(&showValues, "show-values", false, "also print the stored values, which can be secrets")
+
+	var all bool
+	rotate := &cobra.Command{
+		Use:   "rotate [flags] key...",
+		Short: "remove the values of the given keys, so the next render generates new ones",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 && !all {
+				return errors.New("no keys to rotate: give at least one key, or use --all")
+			}
+
+			if len(args) > 0 && all {
+				return errors.New("unable to rotate: give either keys or --all, not both")
+			}
+
+			s, err := load(cmd)
+			if err != nil {
+				return err
+			}
+
+			return s.rotate(cmd.OutOrStdout(), args, all)
+		},
+	}
+
+	rotate.Flags().BoolVar(&all, "all", false, "rotate every entry")
+
+	var force bool
+	prune := &cobra.Command{
+		Use:   "prune",
+		Short: "remove the entries that none of their templates used on their last successful render",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := load(cmd)
+			if err != nil {
+				return err
+			}
+
+			return s.prune(cmd.OutOrStdout(), force)
+		},
+	}
+
+	prune.Flags().BoolVar(&force, "force", false, "prune a state file shared by several templates")
+
+	stateCmd.AddCommand(list, rotate, prune)
+	return stateCmd
+}
+
+// list prints every entry with when it was created and whether any of its
+// templates still uses it
+func (s *state) list(w io.Writer, showValues bool) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	header := []string{"KEY", "CREATED", "STATUS"}
+	if showValues {
+		header = append(header, "VALUE")
+	}
+
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, key := range s.keys() {
+		entry := s.data.Entries[key]
+
+		status := stateUsed
+		if entry.Unused {
+			status = stateUnused
+		}
+
+		row := []string{key, entry.Created.Format(time.RFC3339), status}
+		if showValues {
+			row = append(row, entry.Value)
+		}
+
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+// rotate removes the entries of keys, or every entry when all is set, so
+// the next render stores new values for them
+func (s *state) rotate(w io.Writer, keys []string, all bool) error {
+	if all {
+		keys = s.keys()
+	}
+
+	for _, key := range keys {
+		if _, found := s.data.Entries[key]; !found {
+			return fmt.Errorf("unable to rotate %q: no such key in state file %q", key, s.path)
+		}
+	}
+
+	for _, key := range keys {
+		delete(s.data.Entries, key)
+		fmt.Fprintf(w, "rotated %q, a new value will be generated on the next render\n", key)
+	}
+
+	return s.save()
+}
+
+// prune removes the entries that none of their templates used on their
+// last successful render. Templates are recorded by the path they were
+// rendered with, so pruning a state file shared by several templates
+// requires force.
+func (s *state) prune(w io.Writer, force bool) error {
+	if templates := s.data.Templates; len(templates) > 1 && !force {
+		return fmt.Errorf("unable to prune state file %q: it's shared by %d templates (%s), and an entry can look unused while one of them, given with another path, still needs it: use --force to prune anyway", s.path, len(templates), strings.Join(templates, ", "))
+	}
+
+	for _, key := range s.keys() {
+		if s.data.Entries[key].Unused {
+			delete(s.data.Entries, key)
+			fmt.Fprintf(w, "pruned %q\n", key)
+		}
+	}
+
+	return s.save()
+}
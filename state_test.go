@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStateCommand(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+
+	render := func(tpl string) (string, error) {
+		configs, _ := parseRenderFlags(t, "--state", path, "-x", tpl)
+
+		var buf bytes.Buffer
+		err := command(&buf, *configs)
+		return buf.String(), err
+	}
+
+	tpl := `{{ persistent "db-password" (password 32) }} {{ uuidv4 | persistent "id" }}`
+
+	first, err := render(tpl)
+	if err != nil {
+		t.Fatalf("command() unexpected error: %v", err)
+	}
+
+	second, err := render(tpl)
+	if err != nil {
+		t.Fatalf("command() unexpected error: %v", err)
+	}
+
+	if first != second {
+		t.Errorf("persistent values changed between renders: %q and %q", first, second)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("unable to stat state file: %v", err)
+	}
+
+	if mode := info.Mode().Perm(); mode != stateFileMode {
+		t.Errorf("state file mode = %v, want %v", mode, stateFileMode)
+	}
+
+	// A failed render keeps new values but doesn't mark entries as unused
+	if _, err := render(`{{ persistent "token" (hextoken 16) }}{{ fail "boom" }}`); err == nil {
+		t.Fatal("command() expected an error")
+	}
+
+	s, err := loadState(path)
+	if err != nil {
+		t.Fatalf("loadState() unexpected error: %v", err)
+	}
+
+	if got := s.keys(); strings.Join(got, ",") != "db-password,id,token" {
+		t.Errorf("keys = %q, want db-password, id and token", got)
+	}
+
+	if s.data.Entries["id"].Unused {
+		t.Error("a failed render marked \"id\" as unused")
+	}
+
+	// A successful render marks the keys it didn't use
+	if _, err := render(`{{ persistent "db-password" "ignored" }}`); err != nil {
+		t.Fatalf("command() unexpected error: %v", err)
+	}
+
+	if s, err = loadState(path); err != nil {
+		t.Fatalf("loadState() unexpected error: %v", err)
+	}
+
+	var list bytes.Buffer
+	if err := s.list(&list, false); err != nil {
+		t.Fatalf("list() unexpected error: %v", err)
+	}
+
+	for _, expected := range []string{"db-password", "id", "token"} {
+		if !strings.Contains(list.String(), expected) {
+			t.Errorf("list() = %q, want it to contain %q", list.String(), expected)
+		}
+	}
+
+	if strings.Contains(list.String(), s.data.Entries["db-password"].Value) {
+		t.Errorf("list() printed a value without --show-values: %q", list.String())
+	}
+
+	if err := s.prune(&bytes.Buffer{}, false); err != nil {
+		t.Fatalf("prune() unexpected error: %v", err)
+	}
+
+	if s, err = loadState(path); err != nil {
+		t.Fatalf("loadState() unexpected error: %v", err)
+	}
+
+	if got := s.keys(); strings.Join(got, ",") != "db-password" {
+		t.Errorf("keys after prune = %q, want db-password", got)
+	}
+
+	// Rotating removes the value, so the next render stores a new one
+	if err := s.rotate(&bytes.Buffer{}, []string{"nope"}, false); err == nil {
+		t.Error("rotate() expected an error for an unknown key")
+	}
+
+	if err := s.rotate(&bytes.Buffer{}, []string{"db-password"}, false); err != nil {
+		t.Fatalf("rotate() unexpected error: %v", err)
+	}
+
+	out, err := render(`{{ persistent "db-password" "rotated" }}`)
+	if err != nil {
+		t.Fatalf("command() unexpected error: %v", err)
+	}
+
+	if out != "rotated" {
+		t.Errorf("command() = %q, want %q", out, "rotated")
+	}
+}
+
+func TestStateSharedByTemplates(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	a := filepath.Join(dir, "a.tpl")
+	b := filepath.Join(dir, "b.tpl")
+	skipped := filepath.Join(dir, "skipped.tpl")
+
+	writeTestFile(t, a, `{{ persistent "a" (hextoken 8) }}`, 0o644)
+	writeTestFile(t, b, `{{ persistent "b" (hextoken 8) }}`, 0o644)
+	writeTestFile(t, skipped, "---\nskip: true\n---\n{{ persistent \"skipped\" \"x\" }}", 0o644)
+
+	render := func(tpl string) {
+		t.Helper()
+
+		configs, _ := parseRenderFlags(t, "--state", path, "-f", tpl)
+		if err := command(&bytes.Buffer{}, *configs); err != nil {
+			t.Fatalf("command() unexpected error: %v", err)
+		}
+	}
+
+	unused := func() []string {
+		t.Helper()
+
+		s, err := loadState(path)
+		if err != nil {
+			t.Fatalf("loadState() unexpected error: %v", err)
+		}
+
+		var keys []string
+		for _, key := range s.keys() {
+			if s.data.Entries[key].Unused {
+				keys = append(keys, key)
+			}
+		}
+
+		return keys
+	}
+
+	// Rendering one template doesn't mark the entries of another as unused,
+	// and neither does a template skipped by its front matter
+	render(a)
+	render(b)
+	render(skipped)
+	render(a)
+
+	if got := unused(); len(got) != 0 {
+		t.Errorf("unused keys = %q, want none", got)
+	}
+
+	// An entry is unused once its own template stops using it
+	writeTestFile(t, b, `no longer persistent`, 0o644)
+	render(b)
+
+	if got := unused(); strings.Join(got, ",") != "b" {
+		t.Errorf("unused keys = %q, want b", got)
+	}
+
+	// Pruning a shared state file requires --force
+	s, err := loadState(path)
+	if err != nil {
+		t.Fatalf("loadState() unexpected error: %v", err)
+	}
+
+	if err := s.prune(&bytes.Buffer{}, false); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("prune() error = %v, want an error asking for --force", err)
+	}
+
+	var out bytes.Buffer
+	if err := s.prune(&out, true); err != nil {
+		t.Fatalf("prune() unexpected error: %v", err)
+	}
+
+	if out.String() != "pruned \"b\"\n" {
+		t.Errorf("prune() = %q, want only b pruned", out.String())
+	}
+}
+
+func TestStateSharedByJobs(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	tpl := filepath.Join(dir, "db.tpl")
+	writeTestFile(t, tpl, `{{ persistent (printf "%s-password" .name) (hextoken 8) }}`, 0o644)
+
+	// Jobs rendering the same template with different values don't mark
+	// each other's entries as unused
+	for _, job := range []string{"a", "b", "a"} {
+		configs, _ := parseRenderFlags(t, "--state", path, "-f", tpl, "--set", "name="+job)
+		configs.job = job
+
+		if err := command(&bytes.Buffer{}, *configs); err != nil {
+			t.Fatalf("command() unexpected error: %v", err)
+		}
+	}
+
+	s, err := loadState(path)
+	if err != nil {
+		t.Fatalf("loadState() unexpected error: %v", err)
+	}
+
+	for _, key := range []string{"a-password", "b-password"} {
+		entry, found := s.data.Entries[key]
+		if !found || entry.Unused {
+			t.Errorf("entry %q = %+v, want a used entry", key, entry)
+		}
+	}
+}
+
+func TestStateFrontMatter(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	tpl := filepath.Join(dir, "app.tpl")
+	writeTestFile(t, tpl, "---\noutput: '"+dir+"/{{ persistent \"suffix\" \"app\" }}.txt'\nskip: '{{ persistent \"skip\" \"false\" }}'\n---\n{{ persistent \"body\" \"value\" }}", 0o644)
+
+	configs, _ := parseRenderFlags(t, "--state", path, "-f", tpl)
+	if err := command(&bytes.Buffer{}, *configs); err != nil {
+		t.Fatalf("command() unexpected error: %v", err)
+	}
+
+	s, err := loadState(path)
+	if err != nil {
+		t.Fatalf("loadState() unexpected error: %v", err)
+	}
+
+	// Front matter fields are recorded under the template they belong to
+	if got := s.data.Templates; !reflect.DeepEqual(got, []string{tpl}) {
+		t.Errorf("templates = %q, want only %q", got, tpl)
+	}
+
+	for _, key := range []string{"suffix", "skip", "body"} {
+		entry, found := s.data.Entries[key]
+		if !found || entry.Unused || !reflect.DeepEqual(entry.Templates, []string{tpl}) {
+			t.Errorf("entry %q = %+v, want a used entry of %q", key, entry, tpl)
+		}
+	}
+
+	if err := s.prune(&bytes.Buffer{}, false); err != nil {
+		t.Errorf("prune() unexpected error: %v", err)
+	}
+}
+
+func TestStateHermetic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	tpl := filepath.Join(dir, "secrets.tpl")
+	writeTestFile(t, tpl, `{{ persistentwith "db-password" "password" 32 }}`, 0o644)
+
+	render := func(args ...string) (string, error) {
+		configs, _ := parseRenderFlags(t, append([]string{"--state", path, "-f", tpl}, args...)...)
+
+		var buf bytes.Buffer
+		err := command(&buf, *configs)
+		return buf.String(), err
+	}
+
+	// New secrets can't be generated in hermetic mode, but stored ones
+	// are reproducible
+	if _, err := render("--hermetic"); err == nil || !strings.Contains(err.Error(), "not available in hermetic mode") {
+		t.Errorf("command() error = %v, want a hermetic mode error", err)
+	}
+
+	first, err := render()
+	if err != nil {
+		t.Fatalf("command() unexpected error: %v", err)
+	}
+
+	second, err := render("--hermetic")
+	if err != nil {
+		t.Fatalf("command() unexpected error: %v", err)
+	}
+
+	if len(first) != 32 || first != second {
+		t.Errorf("hermetic render = %q, want the stored value %q", second, first)
+	}
+}
+
+func TestLoadState(t *testing.T) {
+	dir := t.TempDir()
+
+	s, err := loadState(filepath.Join(dir, "missing.json"))
+	if err != nil {
+		t.Fatalf("loadState() unexpected error: %v", err)
+	}
+
+	// Nothing is written when nothing was stored
+	if err := s.save(); err != nil {
+		t.Fatalf("save() unexpected error: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "missing.json")); !os.IsNotExist(err) {
+		t.Errorf("save() created an empty state file")
+	}
+
+	newer := filepath.Join(dir, "newer.json")
+	writeTestFile(t, newer, `{"version": 2, "entries": {}}`, 0o600)
+	if _, err := loadState(newer); err == nil || !strings.Contains(err.Error(), "newer than the supported version") {
+		t.Errorf("loadState() error = %v, want a version error", err)
+	}
+
+	invalid := filepath.Join(dir, "invalid.json")
+	writeTestFile(t, invalid, `{`, 0o600)
+	if _, err := loadState(invalid); err == nil {
+		t.Error("loadState() expected an error for an invalid file")
+	}
+}
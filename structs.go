@@ -1,12 +1,59 @@
 package main
 
+import (
+	"time"
+
+	"github.com/patrickdappollonio/tgen/internal/setflags"
+)
+
 type conf struct {
-	environmentFile   string
-	templateFilePath  string
-	stdinTemplateFile string
-	valuesFile        string
-	strictMode        bool
-	customDelimiters  string
-	setValues         []string
-	setStringValues   []string
+	environmentFiles     []string
+	envCaseSensitive     bool
+	envPrecedence        string
+	templateFilePath     string
+	stdinTemplateFile    string
+	valuesFiles          []string
+	valuesFormat         string
+	withValues           bool
+	schemaFile           string
+	skipSchemaValidation bool
+	strictMode           bool
+	collectErrors        bool
+	errorFormat          string
+	customDelimiters     string
+	setFlags             []setflags.Flag
+	includes             []string
+	inputDir             string
+	outputDir            string
+	outputFile           string
+	splitOutput          string
+	splitName            string
+	watch                bool
+	watchInterval        time.Duration
+	hermetic             bool
+	seed                 *int64
+	clock                string
+	sandbox              bool
+	allowCapabilities    []string
+	denyCapabilities     []string
+	allowFunctions       []string
+	denyFunctions        []string
+	allowRead            []string
+	stateFile            string
+
+	// job is the name of the project job being rendered, if any
+	job string
+
+	// onFileRead is called for every file read by template functions
+	onFileRead func(path string)
+}
+
+// allValuesFiles returns every values file to load, in order. The default
+// values file is always the base layer when --with-values is used.
+func (c conf) allValuesFiles() []string {
+	if c.withValues {
+		return append([]string{"values.yaml"}, c.valuesFiles...)
+	}
+
+	return c.valuesFiles
 }
@@ -15,12 +15,22 @@ import (
 //
 // This is designed to be called from a template.
 func toYAML(v interface{}) string {
-	data, err := yaml.Marshal(v)
+	data, err := ToYAML(v)
 	if err != nil {
 		// Swallow errors inside of a template.
 		return ""
 	}
-	return strings.TrimSuffix(string(data), "\n")
+	return data
+}
+
+// ToYAML marshals v to YAML the same way the "toYAML" template function
+// does, without the trailing newline, but returning any marshal error.
+func ToYAML(v interface{}) (string, error) {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(data), "\n"), nil
 }
 
 func asMap(m any) map[string]any {
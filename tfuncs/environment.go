@@ -5,15 +5,63 @@ import (
 	"strings"
 )
 
-func envstrict(kv map[string]string, strict bool) func(s string) (string, error) {
+// Environment holds the variables read by the env and envdefault functions
+// from environment files, and how they're resolved against the OS
+// environment
+type Environment struct {
+	// Values are the variables loaded from environment files
+	Values map[string]string
+
+	// CaseSensitive looks variables up by their exact name. Otherwise
+	// names are uppercased, and the keys in Values are expected to be too.
+	CaseSensitive bool
+
+	// FilesFirst gives Values precedence over the OS environment
+	FilesFirst bool
+
+	// FilesOnly ignores the OS environment, for hermetic renders
+	FilesOnly bool
+}
+
+// Key returns the name k is looked up as
+func (e Environment) Key(k string) string {
+	if e.CaseSensitive {
+		return k
+	}
+
+	return strings.ToUpper(k)
+}
+
+// Lookup returns the value of the variable k, from the OS environment or
+// the environment files, in the configured order of precedence
+func (e Environment) Lookup(k string) (string, bool) {
+	k = e.Key(k)
+
+	if e.FilesFirst {
+		if v, found := e.Values[k]; found {
+			return v, true
+		}
+	}
+
+	if !e.FilesOnly {
+		if v, found := os.LookupEnv(k); found {
+			return v, true
+		}
+	}
+
+	v, found := e.Values[k]
+	return v, found
+}
+
+func envstrict(env Environment, strict bool) func(s string) (string, error) {
 	return func(s string) (string, error) {
-		return envfunc(s, kv, strict)
+		return envfunc(s, env, strict)
 	}
 }
 
-func envdefault(kv map[string]string) func(k, defval string) (string, error) {
+func envdefault(env Environment) func(k, defval string) (string, error) {
 	return func(k, defval string) (string, error) {
-		if s, _ := envfunc(k, kv, false); s != "" {
+		if s, _ := envfunc(k, env, false); s != "" {
 			return s, nil
 		}
 
@@ -21,19 +69,13 @@ func envdefault(kv map[string]string) func(k, defval string) (string, error) {
 	}
 }
 
-func envfunc(k string, kv map[string]string, strictMode bool) (string, error) {
-	k = strings.ToUpper(k)
-
-	if v, found := os.LookupEnv(k); found {
-		return v, nil
-	}
-
-	if v, found := kv[k]; found {
+func envfunc(k string, env Environment, strictMode bool) (string, error) {
+	if v, found := env.Lookup(k); found {
 		return v, nil
 	}
 
 	if strictMode {
-		return "", ErrVarNotFound(k)
+		return "", ErrVarNotFound(env.Key(k))
 	}
 
 	return "", nil
@@ -36,7 +36,7 @@ func Test_envstrict(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := envstrict(tt.kv, tt.strict)(tt.key)
+			got, err := envstrict(Environment{Values: tt.kv}, tt.strict)(tt.key)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("envstrict() error = %v, wantErr %v", err, tt.wantErr)
@@ -80,7 +80,7 @@ func Test_envdefault(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := envdefault(tt.kv)(tt.key, tt.defval)
+			got, err := envdefault(Environment{Values: tt.kv})(tt.key, tt.defval)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("envdefault() error = %v, wantErr %v", err, tt.wantErr)
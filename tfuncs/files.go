@@ -1,13 +1,10 @@
 package tfuncs
 
 import (
-	"errors"
-	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"sort"
-	"strings"
 )
 
 // ReadFile reads the contents of the file at the specified path and returns it as a string.
@@ -25,34 +22,16 @@ func ReadFile(path string) (string, error) {
 // readLocalFile reads the contents of a file and returns it as a string, but only allows
 // relative paths within the current working directory and its subdirectories.
 // This function provides security by preventing access to files outside the current
-// working directory through path traversal attacks.
+// working directory through path traversal attacks or symbolic links.
 //
 // Returns an error if:
 //   - The path is absolute
 //   - The resolved path is outside the current working directory
+//   - The path, or a symbolic link in it, leads outside the current working directory
 //   - The path points to a directory instead of a file
 //   - The file cannot be read
 func readLocalFile(path string) (string, error) {
-	if filepath.IsAbs(path) {
-		return "", fmt.Errorf("unable to open local file %q: path is absolute, only relative paths are allowed on \"readlocalfile\"", path)
-	}
-
-	wd, err := os.Getwd()
-	if err != nil {
-		return "", err
-	}
-
-	cleanpath := filepath.Join(wd, path)
-
-	if cleanpath == wd {
-		return "", &fs.PathError{Op: "read", Path: cleanpath, Err: errors.New("is a directory")}
-	}
-
-	if !strings.HasPrefix(cleanpath, wd) {
-		return "", fmt.Errorf("unable to open local file %q: file is not under current working directory", cleanpath)
-	}
-
-	return ReadFile(cleanpath)
+	return workingDirReader.readLocalFile(path)
 }
 
 // readDir reads the contents of a directory and returns a sorted slice of entry names.
@@ -85,7 +64,7 @@ func readDir(path string) ([]string, error) {
 // but only allows relative paths within the current working directory and its subdirectories.
 // Directories are returned with a trailing "/" to distinguish them from files.
 // This function provides security by preventing access to directories outside the current
-// working directory through path traversal attacks.
+// working directory through path traversal attacks or symbolic links.
 //
 // The returned slice is sorted alphabetically for consistent output.
 // Symbolic links are not followed.
@@ -93,24 +72,10 @@ func readDir(path string) ([]string, error) {
 // Returns an error if:
 //   - The path is absolute
 //   - The resolved path is outside the current working directory
+//   - The path, or a symbolic link in it, leads outside the current working directory
 //   - The directory cannot be read
 func readLocalDir(path string) ([]string, error) {
-	if filepath.IsAbs(path) {
-		return nil, fmt.Errorf("unable to open local directory %q: path is absolute, only relative paths are allowed on \"readlocaldir\"", path)
-	}
-
-	wd, err := os.Getwd()
-	if err != nil {
-		return nil, err
-	}
-
-	cleanpath := filepath.Join(wd, path)
-
-	if !strings.HasPrefix(cleanpath, wd) {
-		return nil, fmt.Errorf("unable to open local directory %q: directory is not under current working directory", cleanpath)
-	}
-
-	return readDir(cleanpath)
+	return workingDirReader.readLocalDir(path)
 }
 
 // readDirRecursive reads the contents of a directory recursively and returns a sorted slice
@@ -175,7 +140,7 @@ func readDirRecursive(path string) ([]string, error) {
 // relative paths within the current working directory and its subdirectories.
 // Directories are returned with a trailing "/" to distinguish them from files.
 // This function provides security by preventing access to directories outside the current
-// working directory through path traversal attacks.
+// working directory through path traversal attacks or symbolic links.
 //
 // The returned paths use forward slashes for consistency across platforms and are sorted
 // alphabetically for consistent output. The root directory itself is not included in the results.
@@ -184,6 +149,7 @@ func readDirRecursive(path string) ([]string, error) {
 // Returns an error if:
 //   - The path is absolute
 //   - The resolved path is outside the current working directory
+//   - The path, or a symbolic link in it, leads outside the current working directory
 //   - The directory cannot be read
 //
 // For example, if the directory structure is:
@@ -196,20 +162,5 @@ func readDirRecursive(path string) ([]string, error) {
 //
 // The function would return: ["file1.txt", "file2.txt", "subdir/", "subdir/subfile.txt"]
 func readLocalDirRecursive(path string) ([]string, error) {
-	if filepath.IsAbs(path) {
-		return nil, fmt.Errorf("unable to open local directory %q: path is absolute, only relative paths are allowed on \"readlocaldirrecursive\"", path)
-	}
-
-	wd, err := os.Getwd()
-	if err != nil {
-		return nil, err
-	}
-
-	cleanpath := filepath.Join(wd, path)
-
-	if !strings.HasPrefix(cleanpath, wd) {
-		return nil, fmt.Errorf("unable to open local directory %q: directory is not under current working directory", cleanpath)
-	}
-
-	return readDirRecursive(cleanpath)
+	return workingDirReader.readLocalDirRecursive(path)
 }
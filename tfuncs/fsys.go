@@ -0,0 +1,146 @@
+package tfuncs
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// ReadFromFS replaces the file and directory reading functions in funcs with
+// versions that read from fsys instead of the host. Paths are slash
+// separated and relative to the root of fsys, and absolute paths are read
+// from its root too, except on the "readlocal" functions, which still only
+// allow relative paths.
+func ReadFromFS(funcs template.FuncMap, fsys fs.FS) template.FuncMap {
+	f := fsReader{fsys: fsys}
+
+	funcs["readfile"] = f.readFile
+	funcs["readlocalfile"] = func(name string) (string, error) {
+		if strings.HasPrefix(name, "/") {
+			return "", fmt.Errorf("unable to open local file %q: path is absolute, only relative paths are allowed on \"readlocalfile\"", name)
+		}
+
+		return f.readFile(name)
+	}
+
+	funcs["readdir"] = f.readDir
+	funcs["readlocaldir"] = func(name string) ([]string, error) {
+		if strings.HasPrefix(name, "/") {
+			return nil, fmt.Errorf("unable to open local directory %q: path is absolute, only relative paths are allowed on \"readlocaldir\"", name)
+		}
+
+		return f.readDir(name)
+	}
+
+	funcs["readdirrecursive"] = f.readDirRecursive
+	funcs["readlocaldirrecursive"] = func(name string) ([]string, error) {
+		if strings.HasPrefix(name, "/") {
+			return nil, fmt.Errorf("unable to open local directory %q: path is absolute, only relative paths are allowed on \"readlocaldirrecursive\"", name)
+		}
+
+		return f.readDirRecursive(name)
+	}
+
+	return funcs
+}
+
+// fsReader implements the file and directory reading functions on a fs.FS
+type fsReader struct {
+	fsys fs.FS
+}
+
+// name converts a path given to the functions to a name within the file
+// system. Paths can't point outside of it.
+func (f fsReader) name(p string) (string, error) {
+	name := strings.TrimLeft(p, "/")
+	if name == "" {
+		name = "."
+	}
+
+	name = path.Clean(name)
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: "open", Path: p, Err: fs.ErrInvalid}
+	}
+
+	return name, nil
+}
+
+func (f fsReader) readFile(p string) (string, error) {
+	name, err := f.name(p)
+	if err != nil {
+		return "", err
+	}
+
+	contents, err := fs.ReadFile(f.fsys, name)
+	if err != nil {
+		return "", err
+	}
+
+	return string(contents), nil
+}
+
+func (f fsReader) readDir(p string) ([]string, error) {
+	name, err := f.name(p)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := fs.ReadDir(f.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+	for _, entry := range entries {
+		entryName := entry.Name()
+		if entry.IsDir() {
+			entryName += "/"
+		}
+		result = append(result, entryName)
+	}
+
+	sort.Strings(result)
+	return result, nil
+}
+
+func (f fsReader) readDirRecursive(p string) ([]string, error) {
+	root, err := f.name(p)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+
+	err = fs.WalkDir(f.fsys, root, func(walkPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Skip the root directory itself
+		if walkPath == root {
+			return nil
+		}
+
+		relPath := walkPath
+		if root != "." {
+			relPath = strings.TrimPrefix(walkPath, root+"/")
+		}
+
+		// Add trailing slash for directories
+		if d.IsDir() {
+			relPath += "/"
+		}
+
+		result = append(result, relPath)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(result)
+	return result, nil
+}
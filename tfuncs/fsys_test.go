@@ -0,0 +1,65 @@
+package tfuncs
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"text/template"
+)
+
+func Test_ReadFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"file1.txt":          {Data: []byte("content1")},
+		"subdir/subfile.txt": {Data: []byte("subcontent")},
+		"subdir/deep/a.txt":  {Data: []byte("a")},
+	}
+
+	funcs := ReadFromFS(GetFunctions(Environment{}, false), fsys)
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+		wantErr  string
+	}{
+		{name: "readfile", template: `{{ readfile "file1.txt" }}`, expected: "content1"},
+		{name: "readfile absolute", template: `{{ readfile "/subdir/subfile.txt" }}`, expected: "subcontent"},
+		{name: "readlocalfile", template: `{{ readlocalfile "./subdir/../file1.txt" }}`, expected: "content1"},
+		{name: "readlocalfile absolute", template: `{{ readlocalfile "/file1.txt" }}`, wantErr: "only relative paths are allowed"},
+		{name: "readfile outside", template: `{{ readfile "../file1.txt" }}`, wantErr: "invalid argument"},
+		{name: "readfile missing", template: `{{ readfile "missing.txt" }}`, wantErr: "file does not exist"},
+		{name: "readdir", template: `{{ readdir "." }}`, expected: "[file1.txt subdir/]"},
+		{name: "readlocaldir", template: `{{ readlocaldir "subdir" }}`, expected: "[deep/ subfile.txt]"},
+		{name: "readdirrecursive", template: `{{ readdirrecursive "/" }}`, expected: "[file1.txt subdir/ subdir/deep/ subdir/deep/a.txt subdir/subfile.txt]"},
+		{name: "readlocaldirrecursive", template: `{{ readlocaldirrecursive "subdir" }}`, expected: "[deep/ deep/a.txt subfile.txt]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpl, err := template.New("test").Funcs(funcs).Parse(tt.template)
+			if err != nil {
+				t.Fatalf("unable to parse template: %v", err)
+			}
+
+			var buf bytes.Buffer
+			err = tpl.Execute(&buf, nil)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Execute() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Execute() unexpected error: %v", err)
+			}
+
+			if buf.String() != tt.expected {
+				t.Errorf("Execute() = %q, want %q", buf.String(), tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package tfuncs
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Hermetic configures a render that only depends on its declared inputs,
+// so it produces the same output on every machine
+type Hermetic struct {
+	// Roots are the directories the file and directory functions can
+	// read from
+	Roots []string
+
+	// Seed seeds the random functions
+	Seed int64
+
+	// Now is the time returned by the time functions
+	Now time.Time
+}
+
+// nondeterministicFunctions can't be made to return the same result on every
+// call, because they depend on the host or on cryptographic randomness
+var nondeterministicFunctions = []string{
+	"getHostByName",
+	"bcrypt",
+	"htpasswd",
+	"encryptAES",
+	"genPrivateKey",
+	"genCA",
+	"genCAWithKey",
+	"genSelfSignedCert",
+	"genSelfSignedCertWithKey",
+	"genSignedCert",
+	"genSignedCertWithKey",
+	"password",
+	"hextoken",
+	"base64token",
+}
+
+// Apply changes funcs so every function depends only on the configuration
+// of h: files are read only from the roots, random functions are seeded,
+// time functions use a fixed clock, and the functions that can't be made
+// deterministic fail when called. Sprig's "expandenv" is changed to read
+// from env, which should have FilesOnly set.
+func (h Hermetic) Apply(funcs template.FuncMap, env Environment) template.FuncMap {
+	funcs = RestrictFileReads(funcs, h.Roots)
+	funcs = SeedRandom(funcs, h.Seed)
+	funcs = FixClock(funcs, h.Now)
+	funcs = DisableFunctions(funcs, "not available in hermetic mode", nondeterministicFunctions...)
+
+	// Sprig's "expandenv" reads the OS environment directly
+	funcs["expandenv"] = func(s string) string {
+		return os.Expand(s, func(k string) string {
+			v, _ := env.Lookup(k)
+			return v
+		})
+	}
+
+	return funcs
+}
+
+// RestrictFileReads wraps the file and directory reading functions in funcs
+// so they fail for paths outside of roots. Paths are compared after
+// resolving symbolic links, so a link can't point outside of a root.
+func RestrictFileReads(funcs template.FuncMap, roots []string) template.FuncMap {
+	resolved := make([]string, 0, len(roots))
+	for _, root := range roots {
+		resolved = append(resolved, resolvePath(root))
+	}
+
+	check := func(path string) error {
+		if withinRoots(resolvePath(path), resolved) {
+			return nil
+		}
+
+		return fmt.Errorf("unable to read %q: path is outside of the allowed directories: %s", path, strings.Join(quoteAll(roots), ", "))
+	}
+
+	for _, name := range fileFunctions {
+		if fn, ok := funcs[name].(func(string) (string, error)); ok {
+			funcs[name] = func(path string) (string, error) {
+				if err := check(path); err != nil {
+					return "", err
+				}
+
+				return fn(path)
+			}
+		}
+	}
+
+	for _, name := range dirFunctions {
+		if fn, ok := funcs[name].(func(string) ([]string, error)); ok {
+			funcs[name] = func(path string) ([]string, error) {
+				if err := check(path); err != nil {
+					return nil, err
+				}
+
+				return fn(path)
+			}
+		}
+	}
+
+	return funcs
+}
+
+// resolvePath returns the absolute path of path with symbolic links
+// resolved. Paths that don't exist are only made absolute.
+func resolvePath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return resolved
+	}
+
+	return abs
+}
+
+// withinRoots reports whether path is one of roots or is nested under one
+func withinRoots(path string, roots []string) bool {
+	for _, root := range roots {
+		if rel, err := filepath.Rel(root, path); err == nil && filepath.IsLocal(rel) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func quoteAll(items []string) []string {
+	quoted := make([]string, 0, len(items))
+	for _, item := range items {
+		quoted = append(quoted, fmt.Sprintf("%q", item))
+	}
+
+	return quoted
+}
+
+// SeedRandom replaces the random functions in funcs with versions that draw
+// from a single source seeded with seed, so a template calling them in the
+// same order always gets the same results
+func SeedRandom(funcs template.FuncMap, seed int64) template.FuncMap {
+	rnd := rand.New(rand.NewSource(seed))
+
+	funcs["rndstring"] = func(n int) string { return rndgenFrom(rnd, n) }
+	funcs["rnditem"] = func(items []any) any { return rnditemFrom(rnd, items) }
+	funcs["randAlphaNum"] = func(n int) string { return randFrom(rnd, n, alphaNumBytes) }
+	funcs["randAlpha"] = func(n int) string { return randFrom(rnd, n, letterBytes) }
+	funcs["randNumeric"] = func(n int) string { return randFrom(rnd, n, digitBytes) }
+	funcs["randAscii"] = func(n int) string { return randFrom(rnd, n, asciiBytes) }
+	funcs["randInt"] = func(min, max int) int { return rnd.Intn(max-min) + min }
+	funcs["shuffle"] = func(s string) string { return shuffleFrom(rnd, s) }
+	funcs["uuidv4"] = func() string { return uuidv4From(rnd) }
+	funcs["randBytes"] = func(n int) (string, error) {
+		buf := make([]byte, n)
+		rnd.Read(buf)
+		return base64.StdEncoding.EncodeToString(buf), nil
+	}
+
+	return funcs
+}
+
+// FixClock replaces the time functions in funcs with versions that use now
+// as the current time, and now's location instead of the local time zone
+func FixClock(funcs template.FuncMap, now time.Time) template.FuncMap {
+	c := clock{now: now}
+
+	funcs["now"] = func() time.Time { return now }
+	funcs["ago"] = c.ago
+	funcs["date"] = func(format string, date any) string { return c.dateInZone(format, date, "Local") }
+	funcs["htmlDate"] = func(date any) string { return c.dateInZone("2006-01-02", date, "Local") }
+	funcs["dateInZone"] = c.dateInZone
+	funcs["date_in_zone"] = c.dateInZone
+	funcs["htmlDateInZone"] = func(date any, zone string) string { return c.dateInZone("2006-01-02", date, zone) }
+	funcs["toDate"] = func(format, s string) time.Time {
+		t, _ := time.ParseInLocation(format, s, now.Location())
+		return t
+	}
+	funcs["mustToDate"] = func(format, s string) (time.Time, error) {
+		return time.ParseInLocation(format, s, now.Location())
+	}
+
+	// durationRound only depends on the clock when given a time
+	if round, ok := funcs["durationRound"].(func(any) string); ok {
+		funcs["durationRound"] = func(duration any) string {
+			if t, ok := duration.(time.Time); ok {
+				duration = now.Sub(t).String()
+			}
+
+			return round(duration)
+		}
+	}
+
+	return funcs
+}
+
+// DisableFunctions replaces the named functions in funcs with one that
+// fails when called, explaining why with reason. Templates using them still
+// parse.
+func DisableFunctions(funcs template.FuncMap, reason string, names ...string) template.FuncMap {
+	for _, name := range names {
+		if _, found := funcs[name]; !found {
+			continue
+		}
+
+		funcs[name] = func(...any) (any, error) {
+			return nil, fmt.Errorf("function %q is %s", name, reason)
+		}
+	}
+
+	return funcs
+}
+
+// clock implements the Sprig time functions that depend on the current time
+type clock struct {
+	now time.Time
+}
+
+// timeOf converts a date given to the time functions to a time. Like Sprig,
+// integers are seconds since the Unix epoch, and anything else is now.
+func (c clock) timeOf(date any) time.Time {
+	switch date := date.(type) {
+	case time.Time:
+		return date
+	case *time.Time:
+		return *date
+	case int64:
+		return time.Unix(date, 0)
+	case int:
+		return time.Unix(int64(date), 0)
+	case int32:
+		return time.Unix(int64(date), 0)
+	}
+
+	return c.now
+}
+
+// dateInZone formats date in zone, where "Local" is the clock's location
+func (c clock) dateInZone(format string, date any, zone string) string {
+	loc := c.now.Location()
+	if zone != "Local" {
+		var err error
+		if loc, err = time.LoadLocation(zone); err != nil {
+			loc = time.UTC
+		}
+	}
+
+	return c.timeOf(date).In(loc).Format(format)
+}
+
+func (c clock) ago(date any) string {
+	return c.now.Sub(c.timeOf(date)).Round(time.Second).String()
+}
@@ -0,0 +1,150 @@
+package tfuncs
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/Masterminds/sprig/v3"
+)
+
+// executeWith renders content with funcs, returning the output and error
+func executeWith(t *testing.T, funcs template.FuncMap, content string) (string, error) {
+	t.Helper()
+
+	tpl, err := template.New("test").Funcs(funcs).Parse(content)
+	if err != nil {
+		t.Fatalf("unable to parse template: %v", err)
+	}
+
+	var buf bytes.Buffer
+	err = tpl.Execute(&buf, nil)
+	return buf.String(), err
+}
+
+func Test_RestrictFileReads(t *testing.T) {
+	testDir := setupTestDir(t)
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0o644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	if err := os.Symlink(outside, filepath.Join(testDir, "link")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	funcs := RestrictFileReads(GetFunctions(Environment{}, false), []string{filepath.Join(testDir, "subdir")})
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{name: "file within the root", content: `{{ readfile "` + filepath.Join(testDir, "subdir", "subfile1.txt") + `" }}`},
+		{name: "directory within the root", content: `{{ readdirrecursive "` + filepath.Join(testDir, "subdir", "nested") + `" }}`},
+		{name: "the root itself", content: `{{ readdir "` + filepath.Join(testDir, "subdir") + `" }}`},
+		{name: "file outside the root", content: `{{ readfile "` + filepath.Join(testDir, "file1.txt") + `" }}`, wantErr: true},
+		{name: "traversal outside the root", content: `{{ readfile "` + filepath.Join(testDir, "subdir", "..", "file1.txt") + `" }}`, wantErr: true},
+		{name: "directory outside the root", content: `{{ readdir "` + testDir + `" }}`, wantErr: true},
+		{name: "symlink outside the root", content: `{{ readfile "` + filepath.Join(testDir, "link", "secret.txt") + `" }}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := executeWith(t, funcs, tt.content)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err != nil && !strings.Contains(err.Error(), "outside of the allowed directories") {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func Test_SeedRandom(t *testing.T) {
+	content := `{{ rndstring 8 }} {{ rnditem (list 1 2 3) }} {{ randAlphaNum 8 }} {{ randAlpha 8 }} {{ randNumeric 8 }} {{ randAscii 8 }} {{ randInt 1 1000 }} {{ shuffle "abcdefgh" }} {{ uuidv4 }} {{ randBytes 8 }}`
+
+	render := func(seed int64) string {
+		out, err := executeWith(t, SeedRandom(mergeFuncs(GetFunctions(Environment{}, false), sprig.FuncMap()), seed), content)
+		if err != nil {
+			t.Fatalf("unable to execute template: %v", err)
+		}
+
+		return out
+	}
+
+	first, second := render(1), render(1)
+	if first != second {
+		t.Errorf("renders with the same seed differ: %q and %q", first, second)
+	}
+
+	if other := render(2); other == first {
+		t.Errorf("renders with different seeds are the same: %q", first)
+	}
+
+	uuid := strings.Fields(first)[8]
+	if len(uuid) != 36 || uuid[14] != '4' || !strings.ContainsRune("89ab", rune(uuid[19])) {
+		t.Errorf("uuidv4 = %q, want a version 4 UUID", uuid)
+	}
+}
+
+func Test_FixClock(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 30, 0, 0, time.FixedZone("", 2*60*60))
+	funcs := FixClock(sprig.FuncMap(), now)
+
+	out, err := executeWith(t, funcs, `{{ now | date "2006-01-02 15:04 -0700" }}|{{ now | htmlDate }}|{{ dateInZone "15:04" now "UTC" }}|{{ date "2006" "not a date" }}|{{ ago (now | dateModify "-90s") }}|{{ toDate "2006-01-02" "2024-01-01" | date "-0700" }}`)
+	if err != nil {
+		t.Fatalf("unable to execute template: %v", err)
+	}
+
+	if expected := "2024-05-01 10:30 +0200|2024-05-01|08:30|2024|1m30s|+0200"; out != expected {
+		t.Errorf("FixClock() rendered %q, want %q", out, expected)
+	}
+}
+
+func Test_DisableFunctions(t *testing.T) {
+	funcs := DisableFunctions(sprig.FuncMap(), "disabled for testing", "genPrivateKey", "missing")
+
+	if _, found := funcs["missing"]; found {
+		t.Error("DisableFunctions() added a function that didn't exist")
+	}
+
+	_, err := executeWith(t, funcs, `{{ genPrivateKey "rsa" }}`)
+	if err == nil || !strings.Contains(err.Error(), `function "genPrivateKey" is disabled for testing`) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func Test_EnvironmentFilesOnly(t *testing.T) {
+	t.Setenv("TGEN_TEST_HERMETIC", "from-os")
+
+	env := Environment{Values: map[string]string{"FROM_FILE": "yes"}, FilesOnly: true}
+	if _, found := env.Lookup("TGEN_TEST_HERMETIC"); found {
+		t.Error("Lookup() found a variable from the OS environment")
+	}
+
+	if v, _ := env.Lookup("from_file"); v != "yes" {
+		t.Errorf("Lookup() = %q, want %q", v, "yes")
+	}
+}
+
+// mergeFuncs returns a copy of a with the functions of b it doesn't define
+func mergeFuncs(a, b template.FuncMap) template.FuncMap {
+	merged := make(template.FuncMap, len(a)+len(b))
+	for k, v := range b {
+		merged[k] = v
+	}
+
+	for k, v := range a {
+		merged[k] = v
+	}
+
+	return merged
+}
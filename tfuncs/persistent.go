@@ -0,0 +1,108 @@
+package tfuncs
+
+import (
+	"fmt"
+	"reflect"
+	"text/template"
+)
+
+// persistentUnset is "persistent" when no state is set to store values in
+func persistentUnset(key, _ string) (string, error) {
+	return "", errNoState(key)
+}
+
+// persistentWithUnset is "persistentwith" when no state is set to store
+// values in
+func persistentWithUnset(key, _ string, _ ...any) (string, error) {
+	return "", errNoState(key)
+}
+
+func errNoState(key string) error {
+	return fmt.Errorf("unable to store %q: no state file to keep persistent values in, set one with --state", key)
+}
+
+// Persist replaces "persistent" and "persistentwith" in funcs with ones that
+// keep their values in store. Given a key and a function generating a value,
+// store must return the value stored under the key, storing a generated
+// value first when there's none, so a value generated on the first render
+// is reused on the following ones.
+//
+// "persistentwith" takes the name of the generating function and its
+// arguments instead of a value, and only calls it when the key isn't stored
+// yet. The function is looked up in funcs when called, so it's the one with
+// every restriction applied to funcs afterwards, like hermetic mode.
+func Persist(funcs template.FuncMap, store func(key string, generate func() (string, error)) (string, error)) template.FuncMap {
+	funcs["persistent"] = func(key, value string) (string, error) {
+		if key == "" {
+			return "", fmt.Errorf("unable to store persistent value: key must not be empty")
+		}
+
+		return store(key, func() (string, error) { return value, nil })
+	}
+
+	funcs["persistentwith"] = func(key, name string, args ...any) (string, error) {
+		if key == "" {
+			return "", fmt.Errorf("unable to store persistent value: key must not be empty")
+		}
+
+		return store(key, func() (string, error) {
+			fn, found := funcs[name]
+			if !found {
+				return "", fmt.Errorf("unable to generate %q: function %q not defined", key, name)
+			}
+
+			value, err := callFunc(fn, args)
+			if err != nil {
+				return "", fmt.Errorf("unable to generate %q: %w", key, err)
+			}
+
+			return value, nil
+		})
+	}
+
+	return funcs
+}
+
+// callFunc calls fn, a template function, with args, and returns its result
+// formatted as a string. Functions can return a single value, or a value and
+// an error, like the ones template actions can call.
+func callFunc(fn any, args []any) (string, error) {
+	v := reflect.ValueOf(fn)
+	t := v.Type()
+
+	numIn := t.NumIn()
+	if t.IsVariadic() && len(args) < numIn-1 || !t.IsVariadic() && len(args) != numIn {
+		return "", fmt.Errorf("wrong number of arguments: got %d", len(args))
+	}
+
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		want := t.In(min(i, numIn-1))
+		if t.IsVariadic() && i >= numIn-1 {
+			want = want.Elem()
+		}
+
+		if arg == nil {
+			in[i] = reflect.Zero(want)
+			continue
+		}
+
+		value := reflect.ValueOf(arg)
+		if !value.Type().AssignableTo(want) {
+			return "", fmt.Errorf("argument %d has type %s, want %s", i+1, value.Type(), want)
+		}
+
+		in[i] = value
+	}
+
+	out := v.Call(in)
+	if len(out) == 2 && !out[1].IsNil() {
+		return "", out[1].Interface().(error)
+	}
+
+	if len(out) == 0 {
+		return "", nil
+	}
+
+	return fmt.Sprint(out[0].Interface()), nil
+}
@@ -0,0 +1,87 @@
+package tfuncs
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_Persist(t *testing.T) {
+	if _, err := executeWith(t, GetFunctions(Environment{}, false), `{{ persistent "key" "value" }}`); err == nil || !strings.Contains(err.Error(), "no state file") {
+		t.Errorf("error = %v, want a missing state error", err)
+	}
+
+	stored := map[string]string{"existing": "old"}
+	funcs := Persist(GetFunctions(Environment{}, false), func(key string, generate func() (string, error)) (string, error) {
+		if v, found := stored[key]; found {
+			return v, nil
+		}
+
+		value, err := generate()
+		if err != nil {
+			return "", err
+		}
+
+		stored[key] = value
+		return value, nil
+	})
+
+	out, err := executeWith(t, funcs, `{{ persistent "existing" "new" }} {{ "generated" | persistent "created" }} {{ persistent "created" "again" }}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if expected := "old generated generated"; out != expected {
+		t.Errorf("output = %q, want %q", out, expected)
+	}
+
+	if _, err := executeWith(t, funcs, `{{ persistent "" "value" }}`); err == nil {
+		t.Error("expected an error for an empty key")
+	}
+}
+
+func Test_PersistWith(t *testing.T) {
+	if _, err := executeWith(t, GetFunctions(Environment{}, false), `{{ persistentwith "key" "hextoken" 8 }}`); err == nil || !strings.Contains(err.Error(), "no state file") {
+		t.Errorf("error = %v, want a missing state error", err)
+	}
+
+	stored := map[string]string{"existing": "old"}
+	funcs := Persist(GetFunctions(Environment{}, false), func(key string, generate func() (string, error)) (string, error) {
+		if v, found := stored[key]; found {
+			return v, nil
+		}
+
+		value, err := generate()
+		if err != nil {
+			return "", err
+		}
+
+		stored[key] = value
+		return value, nil
+	})
+
+	// Stored values win without calling the generator, so it can fail
+	funcs = DisableFunctions(funcs, "not available", "hextoken")
+	out, err := executeWith(t, funcs, `{{ persistentwith "existing" "hextoken" 8 }} {{ persistentwith "joined" "sprintf" "%s-%s" "a" "b" }}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if expected := "old a-b"; out != expected {
+		t.Errorf("output = %q, want %q", out, expected)
+	}
+
+	for _, tt := range []struct {
+		content string
+		wantErr string
+	}{
+		{content: `{{ persistentwith "new" "hextoken" 8 }}`, wantErr: "not available"},
+		{content: `{{ persistentwith "new" "nope" }}`, wantErr: `function "nope" not defined`},
+		{content: `{{ persistentwith "new" "uppercase" 1 }}`, wantErr: "argument 1 has type int, want string"},
+		{content: `{{ persistentwith "new" "uppercase" }}`, wantErr: "wrong number of arguments"},
+		{content: `{{ persistentwith "" "uppercase" "a" }}`, wantErr: "key must not be empty"},
+	} {
+		if _, err := executeWith(t, funcs, tt.content); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: error = %v, want it to contain %q", tt.content, err, tt.wantErr)
+		}
+	}
+}
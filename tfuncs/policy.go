@@ -0,0 +1,146 @@
+package tfuncs
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// Capability groups the template functions that give access to something
+// outside of the template and its values
+type Capability string
+
+// Capabilities of template functions
+const (
+	CapabilityFS     Capability = "fs"
+	CapabilityEnv    Capability = "env"
+	CapabilityRandom Capability = "random"
+	CapabilityTime   Capability = "time"
+	CapabilityCrypto Capability = "crypto"
+	CapabilityNet    Capability = "net"
+)
+
+// Capabilities lists every capability, in the order they're documented
+var Capabilities = []Capability{CapabilityFS, CapabilityEnv, CapabilityRandom, CapabilityTime, CapabilityCrypto, CapabilityNet}
+
+// capabilityFunctions are the functions, from tgen and Sprig, that need each
+// capability
+var capabilityFunctions = map[Capability][]string{
+	CapabilityFS:  {"readfile", "readlocalfile", "readdir", "readlocaldir", "readdirrecursive", "readlocaldirrecursive", "persistent", "persistentwith"},
+	CapabilityEnv: {"env", "envdefault", "expandenv"},
+	CapabilityRandom: {
+		"rndstring", "rnditem", "randAlphaNum", "randAlpha", "randNumeric", "randAscii",
+		"randInt", "randBytes", "shuffle", "uuidv4",
+	},
+	CapabilityTime: {"now", "ago", "date", "dateInZone", "date_in_zone", "htmlDate", "htmlDateInZone", "durationRound"},
+	CapabilityCrypto: {
+		"bcrypt", "htpasswd", "derivePassword", "encryptAES", "decryptAES", "genPrivateKey",
+		"buildCustomCert", "genCA", "genCAWithKey", "genSelfSignedCert", "genSelfSignedCertWithKey",
+		"genSignedCert", "genSignedCertWithKey", "password", "hextoken", "base64token",
+	},
+	CapabilityNet: {"getHostByName"},
+}
+
+// ParseCapability returns the capability with the given name
+func ParseCapability(name string) (Capability, error) {
+	c := Capability(strings.ToLower(strings.TrimSpace(name)))
+	if slices.Contains(Capabilities, c) {
+		return c, nil
+	}
+
+	names := make([]string, 0, len(Capabilities))
+	for _, c := range Capabilities {
+		names = append(names, string(c))
+	}
+
+	return "", fmt.Errorf("unknown capability %q: valid capabilities are %s", name, strings.Join(names, ", "))
+}
+
+// CapabilityOf returns the capability the named function needs, if any
+func CapabilityOf(name string) (Capability, bool) {
+	for _, c := range Capabilities {
+		if slices.Contains(capabilityFunctions[c], name) {
+			return c, true
+		}
+	}
+
+	return "", false
+}
+
+// Policy decides which template functions can be called. Functions it
+// disables still parse, but fail when called.
+type Policy struct {
+	// Sandbox denies every capability not in Allow
+	Sandbox bool
+
+	// Allow and Deny list capabilities to allow or deny. Deny wins when a
+	// capability is in both.
+	Allow, Deny []Capability
+
+	// AllowFunctions and DenyFunctions list functions to allow or deny
+	// regardless of their capability. DenyFunctions wins when a function
+	// is in both.
+	AllowFunctions, DenyFunctions []string
+}
+
+// Allowed reports whether the capability c is allowed
+func (p Policy) Allowed(c Capability) bool {
+	if slices.Contains(p.Deny, c) {
+		return false
+	}
+
+	return !p.Sandbox || slices.Contains(p.Allow, c)
+}
+
+// Apply replaces the functions in funcs the policy disables with ones that
+// fail with an ErrFunctionDisabled. Names in AllowFunctions or
+// DenyFunctions that aren't in funcs are reported as an error.
+func (p Policy) Apply(funcs template.FuncMap) (template.FuncMap, error) {
+	var unknown []string
+	for _, name := range append(slices.Clone(p.AllowFunctions), p.DenyFunctions...) {
+		if _, found := funcs[name]; !found && !slices.Contains(unknown, name) {
+			unknown = append(unknown, name)
+		}
+	}
+
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown template functions in policy: %s", strings.Join(quoteAll(unknown), ", "))
+	}
+
+	for name := range funcs {
+		capability, needsCapability := CapabilityOf(name)
+
+		switch {
+		case slices.Contains(p.DenyFunctions, name):
+			funcs[name] = disabledFunc(name, "")
+		case slices.Contains(p.AllowFunctions, name):
+		case needsCapability && !p.Allowed(capability):
+			funcs[name] = disabledFunc(name, capability)
+		}
+	}
+
+	return funcs, nil
+}
+
+// ErrFunctionDisabled is returned by the functions disabled by a Policy.
+// Capability is the capability that isn't allowed, and it's empty for
+// functions denied by name.
+type ErrFunctionDisabled struct {
+	Function   string
+	Capability Capability
+}
+
+func (e ErrFunctionDisabled) Error() string {
+	if e.Capability == "" {
+		return fmt.Sprintf("function %q disabled by policy", e.Function)
+	}
+
+	return fmt.Sprintf("function %q disabled by policy: it needs the %q capability", e.Function, e.Capability)
+}
+
+func disabledFunc(name string, capability Capability) func(...any) (any, error) {
+	return func(...any) (any, error) {
+		return nil, ErrFunctionDisabled{Function: name, Capability: capability}
+	}
+}
@@ -0,0 +1,81 @@
+package tfuncs
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Masterminds/sprig/v3"
+)
+
+func Test_Policy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  Policy
+		content string
+		wantErr string
+	}{
+		{name: "no restrictions", policy: Policy{}, content: `{{ env "HOME" }}{{ uuidv4 }}`},
+		{name: "sandbox allows pure functions", policy: Policy{Sandbox: true}, content: `{{ "a" | upper }}{{ list 1 2 | join "," }}`},
+		{name: "sandbox denies environment", policy: Policy{Sandbox: true}, content: `{{ env "HOME" }}`, wantErr: `function "env" disabled by policy: it needs the "env" capability`},
+		{name: "sandbox denies time", policy: Policy{Sandbox: true}, content: `{{ now }}`, wantErr: `function "now" disabled by policy: it needs the "time" capability`},
+		{name: "sandbox allows capability", policy: Policy{Sandbox: true, Allow: []Capability{CapabilityRandom}}, content: `{{ uuidv4 }}`},
+		{name: "deny wins over allow", policy: Policy{Sandbox: true, Allow: []Capability{CapabilityRandom}, Deny: []Capability{CapabilityRandom}}, content: `{{ uuidv4 }}`, wantErr: `"random" capability`},
+		{name: "deny without sandbox", policy: Policy{Deny: []Capability{CapabilityCrypto}}, content: `{{ genPrivateKey "rsa" }}`, wantErr: `function "genPrivateKey" disabled by policy`},
+		{name: "allow function", policy: Policy{Sandbox: true, AllowFunctions: []string{"env"}}, content: `{{ env "HOME" }}`},
+		{name: "deny function", policy: Policy{DenyFunctions: []string{"upper"}}, content: `{{ "a" | upper }}`, wantErr: `function "upper" disabled by policy`},
+		{name: "deny function wins", policy: Policy{AllowFunctions: []string{"env"}, DenyFunctions: []string{"env"}}, content: `{{ env "HOME" }}`, wantErr: `function "env" disabled by policy`},
+		{name: "disabled functions still parse", policy: Policy{Sandbox: true}, content: `{{ if false }}{{ env "HOME" }}{{ end }}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			funcs, err := tt.policy.Apply(mergeFuncs(GetFunctions(Environment{}, false), sprig.FuncMap()))
+			if err != nil {
+				t.Fatalf("Apply() unexpected error: %v", err)
+			}
+
+			_, err = executeWith(t, funcs, tt.content)
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+
+			var disabled ErrFunctionDisabled
+			if !errors.As(err, &disabled) {
+				t.Errorf("error = %v, want an ErrFunctionDisabled", err)
+			}
+		})
+	}
+}
+
+func Test_PolicyUnknownFunctions(t *testing.T) {
+	p := Policy{AllowFunctions: []string{"env", "nope"}, DenyFunctions: []string{"missing"}}
+
+	_, err := p.Apply(GetFunctions(Environment{}, false))
+	if err == nil {
+		t.Fatal("Apply() expected an error for unknown functions")
+	}
+
+	if expected := `unknown template functions in policy: "nope", "missing"`; err.Error() != expected {
+		t.Errorf("Apply() error = %q, want %q", err, expected)
+	}
+}
+
+func Test_ParseCapability(t *testing.T) {
+	if c, err := ParseCapability(" FS "); err != nil || c != CapabilityFS {
+		t.Errorf("ParseCapability() = %q, %v, want %q", c, err, CapabilityFS)
+	}
+
+	if _, err := ParseCapability("disk"); err == nil || !strings.Contains(err.Error(), "valid capabilities are fs, env") {
+		t.Errorf("ParseCapability() error = %v, want an unknown capability error", err)
+	}
+}
@@ -1,6 +1,7 @@
 package tfuncs
 
 import (
+	"fmt"
 	"math/rand"
 	"time"
 	"unsafe"
@@ -15,9 +16,18 @@ const (
 	letterIdxMax  = 63 / letterIdxBits   // # of letter indices fitting in 63 bits
 )
 
+// Character sets used by the seeded versions of Sprig's random functions
+const (
+	digitBytes    = "0123456789"
+	alphaNumBytes = letterBytes + digitBytes
+	asciiBytes    = " !\"#$%&'()*+,-./" + digitBytes + ":;<=>?@ABCDEFGHIJKLMNOPQRSTUVWXYZ[\\]^_`abcdefghijklmnopqrstuvwxyz{|}~"
+)
+
 func rndgen(n int) string {
-	var src = rand.NewSource(time.Now().UnixNano())
+	return rndgenFrom(rand.NewSource(time.Now().UnixNano()), n)
+}
 
+func rndgenFrom(src rand.Source, n int) string {
 	b := make([]byte, n)
 	// A src.Int63() generates 63 random bits, enough for letterIdxMax characters!
 	for i, cache, remain := n-1, src.Int63(), letterIdxMax; i >= 0; {
@@ -36,6 +46,40 @@ func rndgen(n int) string {
 }
 
 func rnditem[T any](items []T) T {
-	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	return rnditemFrom(rand.New(rand.NewSource(time.Now().UnixNano())), items)
+}
+
+func rnditemFrom[T any](rnd *rand.Rand, items []T) T {
 	return items[rnd.Intn(len(items))]
 }
+
+// randFrom returns n characters picked from charset
+func randFrom(rnd *rand.Rand, n int, charset string) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = charset[rnd.Intn(len(charset))]
+	}
+
+	return string(b)
+}
+
+// shuffleFrom returns the characters of s in random order
+func shuffleFrom(rnd *rand.Rand, s string) string {
+	runes := []rune(s)
+	rnd.Shuffle(len(runes), func(i, j int) {
+		runes[i], runes[j] = runes[j], runes[i]
+	})
+
+	return string(runes)
+}
+
+// uuidv4From returns a version 4 UUID made of random bytes from rnd
+func uuidv4From(rnd *rand.Rand) string {
+	var b [16]byte
+	rnd.Read(b[:])
+
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // variant 10
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,143 @@
+package tfuncs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// errOutsideRoots is returned for paths that aren't within any root
+var errOutsideRoots = errors.New("path is outside of the allowed directories")
+
+// rootedReader reads files and directories from within a set of roots.
+// Every read opens its root with os.Root, so neither ".." nor symbolic links
+// can lead outside of it.
+type rootedReader struct {
+	// roots are the absolute directories files can be read from
+	roots []string
+
+	// workingDir adds the current working directory to the roots
+	workingDir bool
+}
+
+// workingDirReader only reads from the current working directory
+var workingDirReader = rootedReader{workingDir: true}
+
+// AllowReads replaces the file and directory reading functions in funcs so
+// they can only read from within roots, opening each root with os.Root so
+// symbolic links can't escape it. The "readlocal" functions still only take
+// relative paths, which can point to the working directory or to a root.
+func AllowReads(funcs template.FuncMap, roots []string) template.FuncMap {
+	r := rootedReader{workingDir: true}
+	for _, root := range roots {
+		if abs, err := filepath.Abs(root); err == nil {
+			root = abs
+		}
+
+		r.roots = append(r.roots, filepath.Clean(root))
+	}
+
+	unconfined := rootedReader{roots: r.roots}
+
+	funcs["readfile"] = unconfined.readFile
+	funcs["readdir"] = unconfined.readDir
+	funcs["readdirrecursive"] = unconfined.readDirRecursive
+	funcs["readlocalfile"] = r.readLocalFile
+	funcs["readlocaldir"] = r.readLocalDir
+	funcs["readlocaldirrecursive"] = r.readLocalDirRecursive
+
+	return funcs
+}
+
+// open returns the first root containing path, opened, and the slash
+// separated name of path within it. Relative paths are resolved from the
+// working directory.
+func (r rootedReader) open(path string) (*os.Root, string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	roots := r.roots
+	if r.workingDir {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, "", err
+		}
+
+		roots = append([]string{wd}, roots...)
+	}
+
+	for _, root := range roots {
+		rel, err := filepath.Rel(root, abs)
+		if err != nil || !filepath.IsLocal(rel) {
+			continue
+		}
+
+		opened, err := os.OpenRoot(root)
+		if err != nil {
+			return nil, "", err
+		}
+
+		return opened, filepath.ToSlash(rel), nil
+	}
+
+	return nil, "", fmt.Errorf("unable to read %q: %w: %s", path, errOutsideRoots, strings.Join(quoteAll(roots), ", "))
+}
+
+func (r rootedReader) readFile(path string) (string, error) {
+	root, name, err := r.open(path)
+	if err != nil {
+		return "", err
+	}
+	defer root.Close()
+
+	return fsReader{fsys: root.FS()}.readFile(name)
+}
+
+func (r rootedReader) readDir(path string) ([]string, error) {
+	root, name, err := r.open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+
+	return fsReader{fsys: root.FS()}.readDir(name)
+}
+
+func (r rootedReader) readDirRecursive(path string) ([]string, error) {
+	root, name, err := r.open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+
+	return fsReader{fsys: root.FS()}.readDirRecursive(name)
+}
+
+func (r rootedReader) readLocalFile(path string) (string, error) {
+	if filepath.IsAbs(path) {
+		return "", fmt.Errorf("unable to open local file %q: path is absolute, only relative paths are allowed on \"readlocalfile\"", path)
+	}
+
+	return r.readFile(path)
+}
+
+func (r rootedReader) readLocalDir(path string) ([]string, error) {
+	if filepath.IsAbs(path) {
+		return nil, fmt.Errorf("unable to open local directory %q: path is absolute, only relative paths are allowed on \"readlocaldir\"", path)
+	}
+
+	return r.readDir(path)
+}
+
+func (r rootedReader) readLocalDirRecursive(path string) ([]string, error) {
+	if filepath.IsAbs(path) {
+		return nil, fmt.Errorf("unable to open local directory %q: path is absolute, only relative paths are allowed on \"readlocaldirrecursive\"", path)
+	}
+
+	return r.readDirRecursive(path)
+}
@@ -0,0 +1,114 @@
+package tfuncs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setupRootsDir creates a working directory "work" with a sibling directory
+// "work-other" and a symbolic link from the former to the latter, and
+// changes to the working directory
+func setupRootsDir(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for _, file := range []string{"work/file.txt", "work/sub/nested.txt", "work-other/secret.txt", "shared/common.txt"} {
+		path := filepath.Join(dir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+
+		if err := os.WriteFile(path, []byte(file), 0o644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	if err := os.Symlink(filepath.Join(dir, "work-other"), filepath.Join(dir, "work", "link")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	if err := os.Symlink("sub/nested.txt", filepath.Join(dir, "work", "inside.txt")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	t.Chdir(filepath.Join(dir, "work"))
+	return dir
+}
+
+func Test_readLocalConfinement(t *testing.T) {
+	setupRootsDir(t)
+
+	tests := []struct {
+		name    string
+		read    func() (any, error)
+		wantErr bool
+	}{
+		{name: "file in the working directory", read: func() (any, error) { return readLocalFile("file.txt") }},
+		{name: "symbolic link within the working directory", read: func() (any, error) { return readLocalFile("inside.txt") }},
+		{name: "traversal that stays within", read: func() (any, error) { return readLocalFile("sub/../file.txt") }},
+		{name: "sibling directory sharing a prefix", read: func() (any, error) { return readLocalFile("../work-other/secret.txt") }, wantErr: true},
+		{name: "symbolic link to a file outside", read: func() (any, error) { return readLocalFile("link/secret.txt") }, wantErr: true},
+		{name: "symbolic link to a directory outside", read: func() (any, error) { return readLocalDir("link") }, wantErr: true},
+		{name: "recursive read through a link outside", read: func() (any, error) { return readLocalDirRecursive("link") }, wantErr: true},
+		{name: "sibling directory listing", read: func() (any, error) { return readLocalDir("../work-other") }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.read()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_AllowReads(t *testing.T) {
+	dir := setupRootsDir(t)
+	shared := filepath.Join(dir, "shared")
+
+	funcs := AllowReads(GetFunctions(Environment{}, false), []string{"../shared"})
+
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+		outside  bool
+		wantErr  bool
+	}{
+		{name: "readfile within a root", content: `{{ readfile "` + filepath.Join(shared, "common.txt") + `" }}`, expected: "shared/common.txt"},
+		{name: "readdir within a root", content: `{{ readdir "` + shared + `" }}`, expected: "[common.txt]"},
+		{name: "readfile outside of the roots", content: `{{ readfile "file.txt" }}`, outside: true},
+		{name: "readfile in a sibling directory", content: `{{ readfile "` + filepath.Join(dir, "shared-other", "x.txt") + `" }}`, outside: true},
+		{name: "readlocalfile in the working directory", content: `{{ readlocalfile "file.txt" }}`, expected: "work/file.txt"},
+		{name: "readlocalfile within a root", content: `{{ readlocalfile "../shared/common.txt" }}`, expected: "shared/common.txt"},
+		{name: "readlocaldirrecursive within a root", content: `{{ readlocaldirrecursive "../shared" }}`, expected: "[common.txt]"},
+		{name: "readlocalfile outside of the roots", content: `{{ readlocalfile "../work-other/secret.txt" }}`, outside: true},
+		{name: "readlocalfile through a link outside", content: `{{ readlocalfile "link/secret.txt" }}`, wantErr: true},
+		{name: "readlocalfile with an absolute path", content: `{{ readlocalfile "` + filepath.Join(shared, "common.txt") + `" }}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := executeWith(t, funcs, tt.content)
+
+			if tt.outside {
+				if !errors.Is(err, errOutsideRoots) {
+					t.Errorf("error = %v, want %v", err, errOutsideRoots)
+				}
+
+				return
+			}
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err == nil && out != tt.expected {
+				t.Errorf("output = %q, want %q", out, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package tfuncs
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Character classes a generated password can draw from
+const (
+	passwordUpper   = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	passwordLower   = "abcdefghijklmnopqrstuvwxyz"
+	passwordDigits  = "0123456789"
+	passwordSymbols = "!#$%&()*+,-.:;<=>?@[]^_{}~"
+)
+
+// passwordClass is a set of characters with the minimum number of them a
+// password must have
+type passwordClass struct {
+	name    string
+	chars   string
+	enabled bool
+	min     int
+}
+
+// passwordOptions are the options accepted by "password"
+var passwordOptions = []string{"upper", "lower", "digits", "symbols", "minUpper", "minLower", "minDigits", "minSymbols", "exclude"}
+
+// password returns a password of the given length drawn from crypto/rand.
+// By default it uses uppercase and lowercase letters, digits and symbols,
+// with at least one of each. The optional map changes that policy:
+//   - "upper", "lower", "digits" and "symbols" enable or disable a class
+//   - "minUpper", "minLower", "minDigits" and "minSymbols" set the minimum
+//     number of characters of a class
+//   - "exclude" is a string of characters to never use, like "0O1lI"
+func password(length int, options ...map[string]any) (string, error) {
+	if length <= 0 {
+		return "", fmt.Errorf("unable to generate password: length must be greater than zero, got %d", length)
+	}
+
+	if len(options) > 1 {
+		return "", fmt.Errorf("unable to generate password: expected a single map of options, got %d", len(options))
+	}
+
+	classes := []*passwordClass{
+		{name: "upper", chars: passwordUpper, enabled: true, min: 1},
+		{name: "lower", chars: passwordLower, enabled: true, min: 1},
+		{name: "digits", chars: passwordDigits, enabled: true, min: 1},
+		{name: "symbols", chars: passwordSymbols, enabled: true, min: 1},
+	}
+
+	if len(options) == 1 {
+		if err := applyPasswordOptions(classes, options[0]); err != nil {
+			return "", fmt.Errorf("unable to generate password: %w", err)
+		}
+	}
+
+	var (
+		all      strings.Builder
+		required []byte
+	)
+
+	for _, c := range classes {
+		if !c.enabled {
+			continue
+		}
+
+		if c.chars == "" {
+			if c.min > 0 {
+				return "", fmt.Errorf("unable to generate password: every character of %q is excluded, but at least %d are required", c.name, c.min)
+			}
+
+			continue
+		}
+
+		all.WriteString(c.chars)
+		for range c.min {
+			ch, err := randomChar(c.chars)
+			if err != nil {
+				return "", err
+			}
+
+			required = append(required, ch)
+		}
+	}
+
+	if all.Len() == 0 {
+		return "", fmt.Errorf("unable to generate password: no characters to choose from")
+	}
+
+	if len(required) > length {
+		return "", fmt.Errorf("unable to generate password: the minimum counts add up to %d characters, more than the length of %d", len(required), length)
+	}
+
+	result := required
+	for len(result) < length {
+		ch, err := randomChar(all.String())
+		if err != nil {
+			return "", err
+		}
+
+		result = append(result, ch)
+	}
+
+	// Shuffle so the required characters aren't always first
+	for i := len(result) - 1; i > 0; i-- {
+		j, err := randomIndex(i + 1)
+		if err != nil {
+			return "", err
+		}
+
+		result[i], result[j] = result[j], result[i]
+	}
+
+	return string(result), nil
+}
+
+// applyPasswordOptions changes the password classes according to options
+func applyPasswordOptions(classes []*passwordClass, options map[string]any) error {
+	for key := range options {
+		if !slices.Contains(passwordOptions, key) {
+			return fmt.Errorf("unknown option %q: valid options are %s", key, strings.Join(passwordOptions, ", "))
+		}
+	}
+
+	if exclude, found := options["exclude"]; found {
+		s, ok := exclude.(string)
+		if !ok {
+			return fmt.Errorf("option \"exclude\" must be a string, got %T", exclude)
+		}
+
+		for _, c := range classes {
+			c.chars = strings.Map(func(r rune) rune {
+				if strings.ContainsRune(s, r) {
+					return -1
+				}
+
+				return r
+			}, c.chars)
+		}
+	}
+
+	for _, c := range classes {
+		if v, found := options[c.name]; found {
+			enabled, err := toBool(v)
+			if err != nil {
+				return fmt.Errorf("option %q: %w", c.name, err)
+			}
+
+			c.enabled = enabled
+			if !enabled {
+				c.min = 0
+			}
+		}
+
+		key := "min" + strings.ToUpper(c.name[:1]) + c.name[1:]
+		if v, found := options[key]; found {
+			min, err := tointE(v)
+			if err != nil {
+				return fmt.Errorf("option %q: %w", key, err)
+			}
+
+			if min < 0 {
+				return fmt.Errorf("option %q must not be negative, got %d", key, min)
+			}
+
+			if min > 0 && !c.enabled {
+				return fmt.Errorf("option %q requires %q to be enabled", key, c.name)
+			}
+
+			c.min = min
+		}
+	}
+
+	return nil
+}
+
+// toBool converts a boolean option, given either as a bool or a string
+func toBool(v any) (bool, error) {
+	switch v := v.(type) {
+	case bool:
+		return v, nil
+	case string:
+		return strconv.ParseBool(v)
+	}
+
+	return false, fmt.Errorf("expected a boolean, got %T", v)
+}
+
+// hextoken returns n bytes from crypto/rand encoded as hexadecimal, so the
+// token is 2*n characters long
+func hextoken(n int) (string, error) {
+	b, err := randomBytes(n)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// base64token returns n bytes from crypto/rand encoded as URL-safe base64,
+// without padding
+func base64token(n int) (string, error) {
+	b, err := randomBytes(n)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func randomBytes(n int) ([]byte, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("unable to generate token: number of bytes must be greater than zero, got %d", n)
+	}
+
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("unable to generate token: %w", err)
+	}
+
+	return b, nil
+}
+
+// randomIndex returns a uniformly distributed number in [0, n) from
+// crypto/rand
+func randomIndex(n int) (int, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, fmt.Errorf("unable to generate random number: %w", err)
+	}
+
+	return int(i.Int64()), nil
+}
+
+// randomChar returns a character of chars picked with crypto/rand
+func randomChar(chars string) (byte, error) {
+	i, err := randomIndex(len(chars))
+	if err != nil {
+		return 0, err
+	}
+
+	return chars[i], nil
+}
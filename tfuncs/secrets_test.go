@@ -0,0 +1,135 @@
+package tfuncs
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// countIn returns how many characters of s are in chars
+func countIn(s, chars string) int {
+	n := 0
+	for _, r := range s {
+		if strings.ContainsRune(chars, r) {
+			n++
+		}
+	}
+
+	return n
+}
+
+func Test_password(t *testing.T) {
+	tests := []struct {
+		name    string
+		length  int
+		options map[string]any
+		check   func(t *testing.T, p string)
+		wantErr string
+	}{
+		{
+			name:   "default policy",
+			length: 16,
+			check: func(t *testing.T, p string) {
+				for _, chars := range []string{passwordUpper, passwordLower, passwordDigits, passwordSymbols} {
+					if countIn(p, chars) == 0 {
+						t.Errorf("password %q has no character of %q", p, chars)
+					}
+				}
+			},
+		},
+		{
+			name:    "disabled classes",
+			length:  32,
+			options: map[string]any{"symbols": false, "upper": "false"},
+			check: func(t *testing.T, p string) {
+				if n := countIn(p, passwordLower+passwordDigits); n != len(p) {
+					t.Errorf("password %q has characters other than lowercase letters and digits", p)
+				}
+			},
+		},
+		{
+			name:    "minimum counts",
+			length:  12,
+			options: map[string]any{"minDigits": 6, "minSymbols": 4},
+			check: func(t *testing.T, p string) {
+				if countIn(p, passwordDigits) != 6 || countIn(p, passwordSymbols) != 4 || countIn(p, passwordUpper) != 1 {
+					t.Errorf("password %q doesn't have 6 digits, 4 symbols and 1 uppercase letter", p)
+				}
+			},
+		},
+		{
+			name:    "exclusions",
+			length:  64,
+			options: map[string]any{"exclude": "0O1lI", "symbols": false},
+			check: func(t *testing.T, p string) {
+				if strings.ContainsAny(p, "0O1lI") {
+					t.Errorf("password %q has excluded characters", p)
+				}
+			},
+		},
+		{name: "zero length", length: 0, wantErr: "length must be greater than zero"},
+		{name: "minimums longer than the length", length: 3, options: map[string]any{"minDigits": 4}, wantErr: "add up to 7 characters"},
+		{name: "every class disabled", length: 8, options: map[string]any{"upper": false, "lower": false, "digits": false, "symbols": false}, wantErr: "no characters to choose from"},
+		{name: "minimum for a disabled class", length: 8, options: map[string]any{"digits": false, "minDigits": 2}, wantErr: `requires "digits" to be enabled`},
+		{name: "class fully excluded", length: 8, options: map[string]any{"exclude": passwordDigits}, wantErr: `every character of "digits" is excluded`},
+		{name: "unknown option", length: 8, options: map[string]any{"length": 8}, wantErr: `unknown option "length"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var options []map[string]any
+			if tt.options != nil {
+				options = append(options, tt.options)
+			}
+
+			p, err := password(tt.length, options...)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("password() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("password() unexpected error: %v", err)
+			}
+
+			if len(p) != tt.length {
+				t.Errorf("password() = %q, want %d characters", p, tt.length)
+			}
+
+			tt.check(t, p)
+		})
+	}
+}
+
+func Test_tokens(t *testing.T) {
+	h, err := hextoken(16)
+	if err != nil {
+		t.Fatalf("hextoken() unexpected error: %v", err)
+	}
+
+	if b, err := hex.DecodeString(h); err != nil || len(b) != 16 {
+		t.Errorf("hextoken() = %q, want 16 hex encoded bytes", h)
+	}
+
+	b64, err := base64token(24)
+	if err != nil {
+		t.Fatalf("base64token() unexpected error: %v", err)
+	}
+
+	if b, err := base64.RawURLEncoding.DecodeString(b64); err != nil || len(b) != 24 {
+		t.Errorf("base64token() = %q, want 24 base64 encoded bytes", b64)
+	}
+
+	if again, _ := hextoken(16); again == h {
+		t.Errorf("hextoken() returned %q twice", h)
+	}
+
+	if _, err := base64token(0); err == nil {
+		t.Error("base64token() expected an error for zero bytes")
+	}
+}
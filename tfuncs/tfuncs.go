@@ -7,7 +7,7 @@ import (
 	"github.com/Masterminds/sprig/v3"
 )
 
-func GetFunctions(virtualKV map[string]string, strict bool) template.FuncMap {
+func GetFunctions(env Environment, strict bool) template.FuncMap {
 	return template.FuncMap{
 		"raw":      raw,
 		"required": requiredField,
@@ -22,8 +22,8 @@ func GetFunctions(virtualKV map[string]string, strict bool) template.FuncMap {
 		"println":   fmt.Sprintln,
 
 		// Environment functions
-		"env":        envstrict(virtualKV, strict),
-		"envdefault": envdefault(virtualKV),
+		"env":        envstrict(env, strict),
+		"envdefault": envdefault(env),
 
 		// Locally defined functions
 		"rndstring":             rndgen,
@@ -43,5 +43,14 @@ func GetFunctions(virtualKV map[string]string, strict bool) template.FuncMap {
 		"asMap":   asMap,
 		"toYAML":  toYAML,
 		"rnditem": rnditem[any],
+
+		// Secrets, drawn from crypto/rand
+		"password":    password,
+		"hextoken":    hextoken,
+		"base64token": base64token,
+
+		// Values kept across renders, see Persist
+		"persistent":     persistentUnset,
+		"persistentwith": persistentWithUnset,
 	}
 }
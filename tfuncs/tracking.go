@@ -0,0 +1,34 @@
+package tfuncs
+
+import "text/template"
+
+// fileFunctions are the template functions that read a single file
+var fileFunctions = []string{"readfile", "readlocalfile"}
+
+// dirFunctions are the template functions that read a directory listing
+var dirFunctions = []string{"readdir", "readlocaldir", "readdirrecursive", "readlocaldirrecursive"}
+
+// TrackFileReads wraps the file and directory reading functions in funcs so
+// every path accessed from a template is reported to onRead before being
+// read. Paths are reported as given by the template, without resolving them.
+func TrackFileReads(funcs template.FuncMap, onRead func(path string)) template.FuncMap {
+	for _, name := range fileFunctions {
+		if fn, ok := funcs[name].(func(string) (string, error)); ok {
+			funcs[name] = func(path string) (string, error) {
+				onRead(path)
+				return fn(path)
+			}
+		}
+	}
+
+	for _, name := range dirFunctions {
+		if fn, ok := funcs[name].(func(string) ([]string, error)); ok {
+			funcs[name] = func(path string) ([]string, error) {
+				onRead(path)
+				return fn(path)
+			}
+		}
+	}
+
+	return funcs
+}
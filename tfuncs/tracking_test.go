@@ -0,0 +1,36 @@
+package tfuncs
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"text/template"
+)
+
+func Test_TrackFileReads(t *testing.T) {
+	testDir := setupTestDir(t)
+
+	var reads []string
+	funcs := TrackFileReads(GetFunctions(Environment{}, false), func(path string) {
+		reads = append(reads, path)
+	})
+
+	file := filepath.Join(testDir, "file1.txt")
+	subdir := filepath.Join(testDir, "subdir")
+
+	tpl, err := template.New("test").Funcs(funcs).Parse(`{{ readfile .file }}{{ readdir .dir }}{{ readdirrecursive .sub }}`)
+	if err != nil {
+		t.Fatalf("unable to parse template: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, map[string]string{"file": file, "dir": testDir, "sub": subdir}); err != nil {
+		t.Fatalf("unable to execute template: %v", err)
+	}
+
+	want := []string{file, testDir, subdir}
+	if !reflect.DeepEqual(reads, want) {
+		t.Errorf("TrackFileReads() reported = %v, want %v", reads, want)
+	}
+}
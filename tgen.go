@@ -1,19 +1,14 @@
 package main
 
 import (
-	"bufio"
 	"bytes"
-	"errors"
+	"context"
 	"fmt"
 	"io"
 	"os"
-	"regexp"
-	"strings"
-	"text/template"
 
-	"github.com/Masterminds/sprig/v3"
+	"github.com/patrickdappollonio/tgen/render"
 	"github.com/patrickdappollonio/tgen/tfuncs"
-	"gopkg.in/yaml.v3"
 
 	"github.com/patrickdappollonio/tgen/internal/setflags"
 )
@@ -21,12 +16,54 @@ import (
 type tgen struct {
 	Strict bool
 
+	// collectErrors keeps rendering in strict mode past missing values,
+	// environment variables and failed required calls, reporting all of
+	// them at once
+	collectErrors bool
+
 	templateFileName    string
 	templateFileContent string
 	yamlValues          map[string]any
 	envValues           map[string]string
 
+	// envCaseSensitive and envFilesFirst control how environment
+	// variables are looked up, see tfuncs.Environment
+	envCaseSensitive bool
+	envFilesFirst    bool
+
+	// hermetic, when set, isolates rendering from the host so it only
+	// depends on the declared inputs
+	hermetic *tfuncs.Hermetic
+
+	// policy, when set, disables the template functions it denies
+	policy *tfuncs.Policy
+
+	// allowRead, when set, are the only directories the file functions
+	// can read from
+	allowRead []string
+
+	// seed, when set, seeds every random function
+	seed *int64
+
+	// state, when set, keeps the values of "persistent" across renders,
+	// recorded under the template and the job rendering it
+	state *state
+	job   string
+
+	// owner, when set, is the template a helper expression like a front
+	// matter field belongs to. Its persistent values are recorded under
+	// the owner, and rendering it doesn't count as rendering the owner.
+	owner string
+
 	preDelimiter, postDelimiter string
+
+	// helpers are parsed alongside the template so the templates they
+	// define can be used from it
+	helpers []helperTemplate
+
+	// onFileRead, when set, is called with every path read by the
+	// file functions during rendering
+	onFileRead func(path string)
 }
 
 func (t *tgen) setTemplate(name, content string) {
@@ -74,32 +111,41 @@ func (t *tgen) loadTemplateFile(overwriteName string, f *os.File) error {
 	return nil
 }
 
-func (t *tgen) loadYAMLValues(yamlpath string) error {
-	if yamlpath == "" {
-		return fmt.Errorf("yaml values file path is empty")
+// loadValues loads a values file in the given format, or the one detected
+// from its extension when format is empty
+func (t *tgen) loadValues(valuespath, format string) error {
+	if valuespath == "" {
+		return fmt.Errorf("values file path is empty")
 	}
 
-	valuesfile := map[string]any{}
-
-	bf, err := tfuncs.ReadFile(yamlpath)
+	format, err := valuesFormatFor(valuespath, format)
 	if err != nil {
 		return err
 	}
 
-	if err := yaml.Unmarshal([]byte(bf), &valuesfile); err != nil {
-		return fmt.Errorf("unable to parse values file %q: %s", yamlpath, err.Error())
+	bf, err := tfuncs.ReadFile(valuespath)
+	if err != nil {
+		return err
 	}
 
-	copied := copyMap(valuesfile)
+	valuesfile, err := t.valuesParser(format)([]byte(bf))
+	if err != nil {
+		return fmt.Errorf("unable to parse %s values file %q: %s", format, valuespath, err.Error())
+	}
 
-	valuesfile["Values"] = copied
-	t.yamlValues = valuesfile
+	// Values files are layered: every new file is deep-merged on top
+	// of the ones loaded before it
+	t.mergeValues(valuesfile)
 	return nil
 }
 
-func (t *tgen) loadEnvValues(envpath string) error {
-	envVars := make(map[string]string)
+// Values for --env-precedence
+const (
+	envPrecedenceOS    = "os"
+	envPrecedenceFiles = "files"
+)
 
+func (t *tgen) loadEnvValues(envpath string) error {
 	if envpath == "" {
 		return nil
 	}
@@ -109,94 +155,60 @@ func (t *tgen) loadEnvValues(envpath string) error {
 		return err
 	}
 
-	sc := bufio.NewScanner(bytes.NewBufferString(data))
-	for sc.Scan() {
-		key, value, err := parseEnvLine(sc.Text())
-		if err != nil {
-			return err
-		}
-
-		if key != "" && value != "" {
-			envVars[key] = value
-		}
-	}
-
-	t.envValues = envVars
-	return nil
-}
-
-// parseSetValues parses Helm-style --set values into a nested map structure with type inference
-func (t *tgen) parseSetValues(setValues []string) (map[string]any, error) {
-	return setflags.ParseSetValues(setValues)
-}
-
-// parseSetStringValues parses Helm-style --set-string values into a nested map structure (all values as strings)
-func (t *tgen) parseSetStringValues(setValues []string) (map[string]any, error) {
-	return setflags.ParseSetStringValues(setValues)
-}
-
-// mergeSetValues parses set values and merges them with existing YAML values
-func (t *tgen) mergeSetValues(setValues []string) error {
-	setParsed, err := t.parseSetValues(setValues)
+	envVars, err := parseEnvFile(data, t.environment())
 	if err != nil {
-		return err
+		return fmt.Errorf("unable to parse environment file %q: %w", envpath, err)
 	}
 
-	// If no YAML values exist, create the structure
-	if t.yamlValues == nil {
-		t.yamlValues = make(map[string]any)
+	// Environment files are layered: keys from every new file replace
+	// the ones loaded before it
+	if t.envValues == nil {
+		t.envValues = make(map[string]string, len(envVars))
 	}
 
-	// Extract the non-Values part from existing YAML values
-	existingValues := make(map[string]any)
-	for k, v := range t.yamlValues {
-		if k != "Values" {
-			existingValues[k] = v
-		}
+	for key, value := range envVars {
+		t.envValues[key] = value
 	}
 
-	// Merge the set values with existing values (excluding Values key)
-	// Set values take precedence over YAML values
-	merged := mergeMap(existingValues, setParsed)
-
-	// Create a copy for the Values key (similar to loadYAMLValues)
-	copied := copyMap(merged)
-	merged["Values"] = copied
-
-	t.yamlValues = merged
 	return nil
 }
 
-// mergeSetStringValues parses set-string values and merges them with existing YAML values
-func (t *tgen) mergeSetStringValues(setStringValues []string) error {
-	setParsed, err := t.parseSetStringValues(setStringValues)
+// environment returns the environment used by the env functions
+func (t *tgen) environment() tfuncs.Environment {
+	return tfuncs.Environment{
+		Values:        t.envValues,
+		CaseSensitive: t.envCaseSensitive,
+		FilesFirst:    t.envFilesFirst,
+		FilesOnly:     t.hermetic != nil,
+	}
+}
+
+// mergeSetFlags parses the values of every set flag, applied in the order
+// they were given, and merges them with existing values
+func (t *tgen) mergeSetFlags(flags []setflags.Flag) error {
+	setParsed, err := setflags.Parse(flags, t.readSetFile)
 	if err != nil {
 		return err
 	}
 
-	// If no YAML values exist, create the structure
-	if t.yamlValues == nil {
-		t.yamlValues = make(map[string]any)
-	}
+	// Set values take precedence over the values loaded so far
+	t.mergeValues(setParsed)
+	return nil
+}
 
-	// Extract the non-Values part from existing YAML values
-	existingValues := make(map[string]any)
-	for k, v := range t.yamlValues {
-		if k != "Values" {
-			existingValues[k] = v
-		}
+// readSetFile reads a file given to --set-file
+func (t *tgen) readSetFile(path string) (string, error) {
+	if t.onFileRead != nil {
+		t.onFileRead(path)
 	}
 
-	// Merge the set-string values with existing values (excluding Values key)
-	// Set-string values take precedence over YAML values
-	merged := mergeMap(existingValues, setParsed)
-
-	// Create a copy for the Values key (similar to loadYAMLValues)
-	copied := copyMap(merged)
-	merged["Values"] = copied
+	return tfuncs.ReadFile(path)
+}
 
-	t.yamlValues = merged
-	return nil
+// mergeValues deep-merges the given values on top of the ones already loaded,
+// with the new values taking precedence, and refreshes the ".Values" alias
+func (t *tgen) mergeValues(values map[string]any) {
+	t.yamlValues = render.MergeValues(t.yamlValues, values)
 }
 
 func (t *tgen) setDelimiters(delimiters string) error {
@@ -212,105 +224,53 @@ func (t *tgen) setDelimiters(delimiters string) error {
 	return nil
 }
 
-func mergeFuncMaps(a, b template.FuncMap) template.FuncMap {
-	if a == nil {
-		a = template.FuncMap{}
+// render renders the template with the loaded values, environment and
+// helpers
+func (t *tgen) render(w io.Writer) error {
+	opts := []render.Option{
+		render.WithTemplate(t.templateFileName, t.templateFileContent),
+		render.WithValues(t.yamlValues),
+		render.WithEnvironment(t.environment()),
+		render.WithDelimiters(t.preDelimiter, t.postDelimiter),
+		render.WithStrict(t.Strict),
+		render.WithCollectErrors(t.collectErrors),
 	}
 
-	for k, v := range b {
-		_, found := a[k]
-		if !found {
-			a[k] = v
-		}
+	for _, h := range t.helpers {
+		opts = append(opts, render.WithHelper(h.name, h.content))
 	}
 
-	return a
-}
-
-func (t *tgen) render(w io.Writer) error {
-	funcs := mergeFuncMaps(tfuncs.GetFunctions(t.envValues, t.Strict), sprig.FuncMap())
-	baseTemplate := template.New(t.templateFileName).Funcs(funcs)
-
-	if t.Strict {
-		baseTemplate = baseTemplate.Option("missingkey=error")
-	} else {
-		baseTemplate = baseTemplate.Option("missingkey=zero")
+	if t.hermetic != nil {
+		opts = append(opts, render.WithHermetic(*t.hermetic))
 	}
 
-	if t.preDelimiter != "" && t.postDelimiter != "" {
-		baseTemplate = baseTemplate.Delims(t.preDelimiter, t.postDelimiter)
+	if t.state != nil {
+		opts = append(opts, render.WithState(t.state.persistent(t.stateTemplate())))
 	}
 
-	var temp bytes.Buffer
-
-	parsed, err := baseTemplate.Parse(t.templateFileContent)
-	if err != nil {
-		return fmt.Errorf("unable to parse template file %q: %s", t.templateFileName, err.Error())
+	if t.seed != nil {
+		opts = append(opts, render.WithSeed(*t.seed))
 	}
 
-	if err := parsed.Execute(&temp, t.yamlValues); err != nil {
-		return t.replaceTemplateRenderError(err)
+	if len(t.allowRead) > 0 {
+		opts = append(opts, render.WithAllowRead(t.allowRead...))
 	}
 
-	if t.Strict {
-		_, err = fmt.Fprint(w, temp.String())
-		return err
+	if t.policy != nil {
+		opts = append(opts, render.WithPolicy(*t.policy))
 	}
 
-	// Due to an unfortunate agreement and lack of behaviour change in the Go standard
-	// library, I'm forced to trim the <no value> string from the output directly.
-	// See helm's engine implementation of this
-	// https://github.com/helm/helm/blob/7ed9d16dc764a5b94b378a7e217865efaa0d9ac8/pkg/engine/engine.go#L267
-	// and the original issue, not solved but closed as wontfix:
-	// https://github.com/golang/go/issues/24963
-	str := strings.ReplaceAll(temp.String(), "<no value>", "")
-	_, err = fmt.Fprint(w, str)
-	return err
-}
-
-// reExtractLocation is used to extract the line number from the error message
-// as a string like "/foo/bar:1:18"
-var reExtractLocation = regexp.MustCompile(`\s([^:]*:\d+:\d+):`)
+	if t.onFileRead != nil {
+		opts = append(opts, render.WithFileReadHook(t.onFileRead))
+	}
 
-func (t *tgen) replaceTemplateRenderError(err error) error {
-	if err == nil {
-		return nil
+	if err := render.New(opts...).Render(context.Background(), w); err != nil {
+		return err
 	}
 
-	// Go templates won't propagate the error message back to the caller, so the
-	// only way to know what happened is to parse the error message and return
-	// a more meaningful error.
-	if t, ok := err.(template.ExecError); ok {
-
-		// Check if we can unwrap the error bubbled up from the template
-		if unwrap := errors.Unwrap(t.Err); unwrap != nil {
-			// The original error does not provide enough contextual information
-			// to know where the error happened, so we need to extract the line
-			// number from the error message
-			matchExpr := reExtractLocation.FindStringSubmatch(t.Err.Error())
-			match := ""
-			if len(matchExpr) > 0 {
-				match = matchExpr[1]
-			}
-
-			switch unwrap.(type) {
-			case *tfuncs.ErrRequired, *tfuncs.ErrVarNotFound:
-				return &templateFuncError{line: match, original: unwrap}
-			default:
-				// do nothing, the next section will take care
-				// of checking for additional items
-			}
-		}
-
-		// If we can't unwrap, it means we're dealing with string-based errors
-		// which are even harder to validate
-		switch {
-		case strings.Contains(err.Error(), "map has no entry for key"):
-			return &missingKeyErr{name: err.Error()[strings.LastIndex(err.Error(), ":")+2:]}
-		default:
-			return t.Err
-		}
+	if t.state != nil && t.owner == "" {
+		t.state.renderedTemplate(t.stateTemplate())
 	}
 
-	return err
+	return nil
 }
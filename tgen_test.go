@@ -1,9 +1,14 @@
 package main
 
 import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
+	"github.com/patrickdappollonio/tgen/internal/maputil"
 	"github.com/patrickdappollonio/tgen/internal/setflags"
 )
 
@@ -119,22 +124,22 @@ func TestMergeSetValues(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tg.yamlValues = tt.existingYAML
-			err := tg.mergeSetValues(tt.setValues)
+			err := tg.mergeSetFlags(setFlagsOf(setflags.KindSet, tt.setValues...))
 
 			if tt.wantErr {
 				if err == nil {
-					t.Errorf("mergeSetValues() expected error but got none")
+					t.Errorf("mergeSetFlags() expected error but got none")
 				}
 				return
 			}
 
 			if err != nil {
-				t.Errorf("mergeSetValues() unexpected error: %v", err)
+				t.Errorf("mergeSetFlags() unexpected error: %v", err)
 				return
 			}
 
 			if !reflect.DeepEqual(tg.yamlValues, tt.expected) {
-				t.Errorf("mergeSetValues() = %v, want %v", tg.yamlValues, tt.expected)
+				t.Errorf("mergeSetFlags() = %v, want %v", tg.yamlValues, tt.expected)
 			}
 		})
 	}
@@ -228,54 +233,35 @@ func TestMergeSetStringValues(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tg.yamlValues = tt.existingYAML
-			err := tg.mergeSetStringValues(tt.setValues)
+			err := tg.mergeSetFlags(setFlagsOf(setflags.KindString, tt.setValues...))
 
 			if tt.wantErr {
 				if err == nil {
-					t.Errorf("mergeSetStringValues() expected error but got none")
+					t.Errorf("mergeSetFlags() expected error but got none")
 				}
 				return
 			}
 
 			if err != nil {
-				t.Errorf("mergeSetStringValues() unexpected error: %v", err)
+				t.Errorf("mergeSetFlags() unexpected error: %v", err)
 				return
 			}
 
 			if !reflect.DeepEqual(tg.yamlValues, tt.expected) {
-				t.Errorf("mergeSetStringValues() = %v, want %v", tg.yamlValues, tt.expected)
+				t.Errorf("mergeSetFlags() = %v, want %v", tg.yamlValues, tt.expected)
 			}
 		})
 	}
 }
 
-// Test that the tgen methods properly delegate to the internal package
-func TestTgenParseSetValues(t *testing.T) {
-	tg := &tgen{}
-	result, err := tg.parseSetValues([]string{"key=value"})
-	if err != nil {
-		t.Errorf("parseSetValues() unexpected error: %v", err)
-		return
+// setFlagsOf returns set flags of the given kind, one per value
+func setFlagsOf(kind string, values ...string) []setflags.Flag {
+	flags := make([]setflags.Flag, 0, len(values))
+	for _, v := range values {
+		flags = append(flags, setflags.Flag{Kind: kind, Value: v})
 	}
 
-	expected := map[string]any{"key": "value"}
-	if !reflect.DeepEqual(result, expected) {
-		t.Errorf("parseSetValues() = %v, want %v", result, expected)
-	}
-}
-
-func TestTgenParseSetStringValues(t *testing.T) {
-	tg := &tgen{}
-	result, err := tg.parseSetStringValues([]string{"key=123"})
-	if err != nil {
-		t.Errorf("parseSetStringValues() unexpected error: %v", err)
-		return
-	}
-
-	expected := map[string]any{"key": "123"}
-	if !reflect.DeepEqual(result, expected) {
-		t.Errorf("parseSetStringValues() = %v, want %v", result, expected)
-	}
+	return flags
 }
 
 // Test advanced functionality through the internal package
@@ -304,3 +290,103 @@ func TestAdvancedSetFunctionality(t *testing.T) {
 		t.Errorf("ParseSetValues() = %v, want %v", result, expected)
 	}
 }
+
+func TestLoadYAMLValuesLayered(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"values.yaml":          "app:\n  name: myapp\n  replicas: 1\n  debug: false\nregion: us-east-1\n",
+		"values.prod.yaml":     "app:\n  replicas: 3\nregion: eu-west-1\n",
+		"values.override.yaml": "app:\n  debug: true\n",
+	}
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to create values file: %v", err)
+		}
+	}
+
+	tg := &tgen{}
+	for _, name := range []string{"values.yaml", "values.prod.yaml", "values.override.yaml"} {
+		if err := tg.loadValues(filepath.Join(dir, name), ""); err != nil {
+			t.Fatalf("loadValues() unexpected error: %v", err)
+		}
+	}
+
+	if err := tg.mergeSetFlags(setFlagsOf(setflags.KindSet, "region=ap-south-1")); err != nil {
+		t.Fatalf("mergeSetFlags() unexpected error: %v", err)
+	}
+
+	values := map[string]any{
+		"app": map[string]any{
+			"name":     "myapp",
+			"replicas": 3,
+			"debug":    true,
+		},
+		"region": "ap-south-1",
+	}
+
+	expected := maputil.Copy(values)
+	expected["Values"] = values
+
+	if !reflect.DeepEqual(tg.yamlValues, expected) {
+		t.Errorf("layered values = %v, want %v", tg.yamlValues, expected)
+	}
+}
+
+func TestEnvironmentFiles(t *testing.T) {
+	t.Setenv("TGEN_TEST_REGION", "from-os")
+
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.env")
+	prod := filepath.Join(dir, "prod.env")
+	writeTestFile(t, base, "name=base\nhttp_proxy=lower\nTGEN_TEST_REGION=from-file\n", 0o644)
+	writeTestFile(t, prod, "NAME=prod\nHTTP_PROXY=upper\nURL=${NAME}.example.com\n", 0o644)
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "later files take precedence",
+			args:     []string{"-x", `{{ env "name" }} {{ env "http_proxy" }} {{ env "URL" }}`},
+			expected: "prod upper prod.example.com",
+		},
+		{
+			name:     "case sensitive keys don't collide",
+			args:     []string{"--env-case-sensitive", "-x", `{{ env "name" }} {{ env "NAME" }} {{ env "http_proxy" }} {{ env "HTTP_PROXY" }}`},
+			expected: "base prod lower upper",
+		},
+		{
+			name:     "the OS environment wins by default",
+			args:     []string{"-x", `{{ env "TGEN_TEST_REGION" }}`},
+			expected: "from-os",
+		},
+		{
+			name:     "environment files can take precedence",
+			args:     []string{"--env-precedence", "files", "-x", `{{ env "TGEN_TEST_REGION" }}`},
+			expected: "from-file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configs, _ := parseRenderFlags(t, append([]string{"-e", base, "-e", prod}, tt.args...)...)
+
+			var buf bytes.Buffer
+			if err := command(&buf, *configs); err != nil {
+				t.Fatalf("command() unexpected error: %v", err)
+			}
+
+			if buf.String() != tt.expected {
+				t.Errorf("command() = %q, want %q", buf.String(), tt.expected)
+			}
+		})
+	}
+
+	configs, _ := parseRenderFlags(t, "--env-precedence", "nope", "-x", "")
+	if err := command(io.Discard, *configs); err == nil {
+		t.Error("command() expected an error for an unknown precedence")
+	}
+}
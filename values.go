@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/patrickdappollonio/tgen/internal/dotenv"
+	"github.com/patrickdappollonio/tgen/internal/maputil"
+	"github.com/patrickdappollonio/tgen/internal/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Formats supported for values files
+const (
+	valuesYAML       = "yaml"
+	valuesJSON       = "json"
+	valuesTOML       = "toml"
+	valuesDotenv     = "dotenv"
+	valuesProperties = "properties"
+)
+
+// valuesExtensions maps file extensions to the format of the values file
+var valuesExtensions = map[string]string{
+	".yaml":       valuesYAML,
+	".yml":        valuesYAML,
+	".json":       valuesJSON,
+	".toml":       valuesTOML,
+	".env":        valuesDotenv,
+	".properties": valuesProperties,
+}
+
+// valuesParsers decode the contents of a values file in each format. Dotenv
+// files parsed from here only expand variables defined earlier in the file,
+// see valuesParser for the one that also reads the environment.
+var valuesParsers = map[string]func(data []byte) (map[string]any, error){
+	valuesYAML:       parseYAMLValues,
+	valuesJSON:       parseJSONValues,
+	valuesTOML:       toml.Parse,
+	valuesDotenv:     func(data []byte) (map[string]any, error) { return parseDotenvValues(data, nil) },
+	valuesProperties: parsePropertiesValues,
+}
+
+// valuesParser returns the parser for values files in format. Variables in
+// dotenv files resolve the same way they do in files given to --environment,
+// so --hermetic keeps the host environment out of them.
+func (t *tgen) valuesParser(format string) func(data []byte) (map[string]any, error) {
+	if format == valuesDotenv {
+		lookup := t.environment().Lookup
+		return func(data []byte) (map[string]any, error) {
+			return parseDotenvValues(data, lookup)
+		}
+	}
+
+	return valuesParsers[format]
+}
+
+// valuesFormatFor returns the format of the values file at path. An explicit
+// format takes precedence, otherwise it's detected from the file extension,
+// defaulting to YAML for unknown extensions.
+func valuesFormatFor(path, format string) (string, error) {
+	if format != "" {
+		if _, found := valuesParsers[format]; !found {
+			return "", fmt.Errorf("unknown values format %q: valid options are %q, %q, %q, %q or %q", format, valuesYAML, valuesJSON, valuesTOML, valuesDotenv, valuesProperties)
+		}
+
+		return format, nil
+	}
+
+	name := strings.ToLower(filepath.Base(path))
+	if format, found := valuesExtensions[filepath.Ext(name)]; found {
+		return format, nil
+	}
+
+	// Files like ".env" have no extension but a well known name
+	if name == ".env" || strings.HasPrefix(name, ".env.") {
+		return valuesDotenv, nil
+	}
+
+	return valuesYAML, nil
+}
+
+func parseYAMLValues(data []byte) (map[string]any, error) {
+	values := map[string]any{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// parseJSONValues decodes a JSON object. Numbers are decoded like YAML
+// does, as int when they're integers and float64 otherwise.
+func parseJSONValues(data []byte) (map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var values any
+	if err := dec.Decode(&values); err != nil {
+		if errors.Is(err, io.EOF) {
+			return map[string]any{}, nil
+		}
+
+		return nil, err
+	}
+
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("unexpected data after the top-level JSON value")
+	}
+
+	m, ok := maputil.NormalizeJSON(values).(map[string]any)
+	if !ok {
+		return nil, errors.New("the top-level JSON value must be an object")
+	}
+
+	return m, nil
+}
+
+// parseDotenvValues decodes an environment file into a flat map of strings,
+// resolving variables not defined in the file with lookup. Unlike
+// environment files given to --environment, keys keep their case.
+func parseDotenvValues(data []byte, lookup func(key string) (string, bool)) (map[string]any, error) {
+	parsed, err := dotenv.Parse(data, lookup)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]any, len(parsed))
+	for _, entry := range parsed {
+		values[entry.Key] = entry.Value
+	}
+
+	return values, nil
+}
+
+// parsePropertiesValues decodes a Java-style .properties file. Values are
+// kept as strings, and dotted keys like "db.host" become nested maps, the
+// same shape "db: {host: ...}" has in YAML.
+func parsePropertiesValues(data []byte) (map[string]any, error) {
+	values := map[string]any{}
+
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineno := i + 1
+		line := strings.TrimLeft(lines[i], " \t\f")
+
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+
+		// A line ending with an odd number of backslashes continues on
+		// the next line, ignoring its leading whitespace
+		for isContinued(line) && i+1 < len(lines) {
+			i++
+			line = line[:len(line)-1] + strings.TrimLeft(lines[i], " \t\f")
+		}
+
+		rawKey, rawValue := splitProperty(line)
+
+		key, err := unescapeProperty(rawKey)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineno, err)
+		}
+
+		value, err := unescapeProperty(rawValue)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineno, err)
+		}
+
+		if err := setPropertyValue(values, key, value); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineno, err)
+		}
+	}
+
+	return values, nil
+}
+
+func isContinued(line string) bool {
+	backslashes := len(line) - len(strings.TrimRight(line, `\`))
+	return backslashes%2 == 1
+}
+
+// splitProperty splits a line at the first unescaped "=", ":" or whitespace
+func splitProperty(line string) (string, string) {
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '=', ':':
+			return line[:i], strings.TrimLeft(line[i+1:], " \t\f")
+		case ' ', '\t', '\f':
+			rest := strings.TrimLeft(line[i:], " \t\f")
+			if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+				rest = strings.TrimLeft(rest[1:], " \t\f")
+			}
+
+			return line[:i], rest
+		}
+	}
+
+	return line, ""
+}
+
+func unescapeProperty(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			sb.WriteByte(s[i])
+			continue
+		}
+
+		i++
+		switch s[i] {
+		case 't':
+			sb.WriteByte('\t')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'u':
+			if i+5 > len(s) {
+				return "", fmt.Errorf("invalid unicode escape %q", s[i-1:])
+			}
+
+			n, err := strconv.ParseUint(s[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", fmt.Errorf("invalid unicode escape %q", s[i-1:i+5])
+			}
+
+			sb.WriteRune(rune(n))
+			i += 4
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// setPropertyValue sets value at the dotted key within values
+func setPropertyValue(values map[string]any, key, value string) error {
+	parts := strings.Split(key, ".")
+	current := values
+
+	for i, part := range parts[:len(parts)-1] {
+		switch next := current[part].(type) {
+		case nil:
+			m := map[string]any{}
+			current[part] = m
+			current = m
+		case map[string]any:
+			current = next
+		default:
+			return fmt.Errorf("key %q conflicts with %q, which already has a value", key, strings.Join(parts[:i+1], "."))
+		}
+	}
+
+	last := parts[len(parts)-1]
+	if _, isMap := current[last].(map[string]any); isMap {
+		return fmt.Errorf("key %q conflicts with keys nested under it", key)
+	}
+
+	current[last] = value
+	return nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/patrickdappollonio/tgen/internal/maputil"
+)
+
+func TestValuesFormatFor(t *testing.T) {
+	tests := []struct {
+		path     string
+		format   string
+		expected string
+		wantErr  bool
+	}{
+		{path: "values.yaml", expected: valuesYAML},
+		{path: "values.YML", expected: valuesYAML},
+		{path: "values.json", expected: valuesJSON},
+		{path: "config/app.toml", expected: valuesTOML},
+		{path: "prod.env", expected: valuesDotenv},
+		{path: ".env", expected: valuesDotenv},
+		{path: ".env.local", expected: valuesDotenv},
+		{path: "app.properties", expected: valuesProperties},
+		{path: "values", expected: valuesYAML},
+		{path: "values.txt", format: valuesJSON, expected: valuesJSON},
+		{path: "values.yaml", format: "xml", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path+"/"+tt.format, func(t *testing.T) {
+			got, err := valuesFormatFor(tt.path, tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("valuesFormatFor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.expected {
+				t.Errorf("valuesFormatFor() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLoadValuesFormats(t *testing.T) {
+	expected := map[string]any{
+		"name":     "app",
+		"replicas": 3,
+		"ratio":    0.5,
+		"debug":    true,
+		"ports":    []any{80, 443},
+		"db":       map[string]any{"host": "localhost", "port": 5432},
+	}
+
+	files := map[string]string{
+		"values.yaml": "name: app\nreplicas: 3\nratio: 0.5\ndebug: true\nports: [80, 443]\ndb:\n  host: localhost\n  port: 5432\n",
+		"values.json": `{"name": "app", "replicas": 3, "ratio": 0.5, "debug": true, "ports": [80, 443], "db": {"host": "localhost", "port": 5432}}`,
+		"values.toml": "name = \"app\"\nreplicas = 3\nratio = 0.5\ndebug = true\nports = [80, 443]\n\n[db]\nhost = \"localhost\"\nport = 5432\n",
+	}
+
+	dir := t.TempDir()
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			writeTestFile(t, path, content, 0o644)
+
+			tg := &tgen{}
+			if err := tg.loadValues(path, ""); err != nil {
+				t.Fatalf("loadValues() unexpected error: %v", err)
+			}
+
+			values := maputil.Copy(tg.yamlValues)
+			delete(values, "Values")
+
+			if !reflect.DeepEqual(values, expected) {
+				t.Errorf("loadValues() = %#v, want %#v", values, expected)
+			}
+
+			tg.setTemplate("test", "{{ .db.host }}:{{ .Values.db.port }}")
+
+			var buf bytes.Buffer
+			if err := tg.render(&buf); err != nil {
+				t.Fatalf("render() unexpected error: %v", err)
+			}
+
+			if buf.String() != "localhost:5432" {
+				t.Errorf("render() = %q, want %q", buf.String(), "localhost:5432")
+			}
+		})
+	}
+}
+
+func TestParsePropertiesValues(t *testing.T) {
+	content := `# comment
+! another comment
+app.name = My App
+app.description: A very \
+    long description
+db.host localhost
+db.port=5432
+path=C:\\Users\\app
+greeting=caf\u00e9
+empty=
+`
+
+	got, err := parsePropertiesValues([]byte(content))
+	if err != nil {
+		t.Fatalf("parsePropertiesValues() unexpected error: %v", err)
+	}
+
+	expected := map[string]any{
+		"app":      map[string]any{"name": "My App", "description": "A very long description"},
+		"db":       map[string]any{"host": "localhost", "port": "5432"},
+		"path":     `C:\Users\app`,
+		"greeting": "café",
+		"empty":    "",
+	}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("parsePropertiesValues() = %#v, want %#v", got, expected)
+	}
+
+	if _, err := parsePropertiesValues([]byte("db=x\ndb.host=y\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("parsePropertiesValues() error = %v, want a conflict on line 2", err)
+	}
+}
+
+func TestParseJSONValuesErrors(t *testing.T) {
+	for _, content := range []string{`[1, 2]`, `{"a": 1} {"b": 2}`, `{"a": }`} {
+		if _, err := parseJSONValues([]byte(content)); err == nil {
+			t.Errorf("parseJSONValues(%s) expected error but got none", content)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// defaultWatchInterval is how often watched files are polled for changes
+const defaultWatchInterval = 500 * time.Millisecond
+
+// fileState is the subset of a file's metadata used to detect changes
+type fileState struct {
+	exists  bool
+	size    int64
+	modTime int64
+	mode    fs.FileMode
+}
+
+// watchCommand renders the template like command does, then keeps polling
+// every input of the render, re-rendering whenever one of them changes.
+// Rendering errors are printed to errw instead of stopping the watch. It
+// only returns when ctx is cancelled or the configuration is invalid.
+func watchCommand(ctx context.Context, w, errw io.Writer, c conf) error {
+	if c.templateFilePath == "-" {
+		return errors.New("unable to watch a template read from stdin, use --file with a path instead")
+	}
+
+	interval := c.watchInterval
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+
+	for {
+		var reads []string
+		c.onFileRead = func(path string) {
+			reads = append(reads, path)
+		}
+
+		if err := command(w, c); err != nil {
+			var conflict *conflictingArgsError
+			var missing *missingArgError
+			if errors.As(err, &conflict) || errors.As(err, &missing) {
+				return err
+			}
+
+			writeError(errw, err, c.errorFormat)
+		}
+
+		watched := append(c.watchedPaths(), reads...)
+		if err := waitForChange(ctx, watched, c.ignoredPaths(), interval); err != nil {
+			if errors.Is(err, context.Canceled) {
+				return nil
+			}
+
+			return err
+		}
+	}
+}
+
+// watchedPaths returns the files and directories declared on the command line
+// that affect the rendered output
+func (c conf) watchedPaths() []string {
+	var paths []string
+
+	if c.templateFilePath != "" {
+		paths = append(paths, c.templateFilePath)
+	}
+
+	if c.inputDir != "" {
+		paths = append(paths, c.inputDir)
+	}
+
+	paths = append(paths, c.environmentFiles...)
+	paths = append(paths, c.includes...)
+
+	if schemapath := c.schemaPath(); schemapath != "" {
+		paths = append(paths, schemapath)
+	}
+
+	paths = append(paths, c.allValuesFiles()...)
+	return paths
+}
+
+// ignoredPaths returns the paths written by tgen itself, which must not
+// trigger a new render when they change
+func (c conf) ignoredPaths() []string {
+	var paths []string
+
+	for _, p := range []string{c.outputFile, c.outputDir, c.splitOutput} {
+		if p == "" {
+			continue
+		}
+
+		if abs, err := filepath.Abs(p); err == nil {
+			paths = append(paths, abs)
+		}
+	}
+
+	return paths
+}
+
+// waitForChange polls paths every interval until one of them changes. To
+// debounce editors that write files in several steps, it only returns once
+// the files have stopped changing for a full interval.
+func waitForChange(ctx context.Context, paths, ignored []string, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	initial := snapshot(paths, ignored)
+	last := initial
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		current := snapshot(paths, ignored)
+		changed := !maps.Equal(current, initial)
+		settled := maps.Equal(current, last)
+		last = current
+
+		if changed && settled {
+			return nil
+		}
+	}
+}
+
+// snapshot records the state of every path. Directories are walked so
+// changes to any file within them are noticed. Paths under ignored are
+// skipped.
+func snapshot(paths, ignored []string) map[string]fileState {
+	states := make(map[string]fileState)
+
+	for _, p := range paths {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			continue
+		}
+
+		info, err := os.Stat(abs)
+		if err != nil {
+			states[abs] = fileState{}
+			continue
+		}
+
+		if !info.IsDir() {
+			states[abs] = newFileState(info)
+			continue
+		}
+
+		filepath.WalkDir(abs, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+
+			for _, skip := range ignored {
+				if path == skip {
+					if d.IsDir() {
+						return filepath.SkipDir
+					}
+
+					return nil
+				}
+			}
+
+			if info, err := d.Info(); err == nil {
+				states[path] = newFileState(info)
+			}
+
+			return nil
+		})
+	}
+
+	return states
+}
+
+func newFileState(info fs.FileInfo) fileState {
+	return fileState{
+		exists:  true,
+		size:    info.Size(),
+		modTime: info.ModTime().UnixNano(),
+		mode:    info.Mode(),
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent use
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func waitForOutput(t *testing.T, b *syncBuffer, want string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if strings.Contains(b.String(), want) {
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("timed out waiting for %q, got %q", want, b.String())
+}
+
+func TestWatchCommand(t *testing.T) {
+	dir := t.TempDir()
+	tpl := filepath.Join(dir, "template.txt")
+	values := filepath.Join(dir, "values.yaml")
+	extra := filepath.Join(dir, "extra.txt")
+
+	writeTestFile(t, tpl, `{{ .name }}-{{ readfile "`+extra+`" }};`, 0o644)
+	writeTestFile(t, values, "name: first", 0o644)
+	writeTestFile(t, extra, "one", 0o644)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var stdout, stderr syncBuffer
+	done := make(chan error, 1)
+	go func() {
+		done <- watchCommand(ctx, &stdout, &stderr, conf{
+			templateFilePath: tpl,
+			valuesFiles:      []string{values},
+			watchInterval:    20 * time.Millisecond,
+		})
+	}()
+
+	waitForOutput(t, &stdout, "first-one;")
+
+	// Changes to the values file trigger a new render
+	writeTestFile(t, values, "name: second", 0o644)
+	waitForOutput(t, &stdout, "second-one;")
+
+	// So do changes to files read from within the template
+	writeTestFile(t, extra, "two", 0o644)
+	waitForOutput(t, &stdout, "second-two;")
+
+	// Errors are reported but don't stop watching
+	writeTestFile(t, tpl, `{{ .name `, 0o644)
+	waitForOutput(t, &stderr, "Error:")
+
+	writeTestFile(t, tpl, `fixed {{ .name }};`, 0o644)
+	waitForOutput(t, &stdout, "fixed second;")
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("watchCommand() unexpected error: %v", err)
+	}
+}
+
+func TestWatchCommandStdin(t *testing.T) {
+	err := watchCommand(context.Background(), nil, nil, conf{templateFilePath: "-"})
+	if err == nil {
+		t.Error("watchCommand() expected error but got none")
+	}
+}
+
+func TestSnapshotIgnoresOutput(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "out")
+
+	writeTestFile(t, filepath.Join(dir, "in.txt"), "a", 0o644)
+	writeTestFile(t, filepath.Join(output, "in.txt"), "a", 0o644)
+
+	before := snapshot([]string{dir}, []string{output})
+	writeTestFile(t, filepath.Join(output, "in.txt"), "changed", 0o644)
+
+	if err := os.Chtimes(filepath.Join(output, "in.txt"), time.Now().Add(time.Hour), time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("unable to change file times: %v", err)
+	}
+
+	after := snapshot([]string{dir}, []string{output})
+	if len(before) != len(after) {
+		t.Fatalf("snapshot() sizes differ: %d vs %d", len(before), len(after))
+	}
+
+	for k, v := range before {
+		if after[k] != v {
+			t.Errorf("snapshot() changed for %q", k)
+		}
+	}
+}